@@ -39,17 +39,18 @@ func setAlertOnDnsChangeSync(changeInfo *route53.ChangeInfo, sess *session.Sessi
 }
 
 // Wait and notify on proxy task running.
-// Then on success create DNS for the proxy.
+// Then, once the proxy is accepting connections, create DNS for the proxy.
 // TODO: wants refactoring too much going on here. 
 // perhaps the thing to do is turn this into a wait-on-with-notify taking a function
 // to execute when the dns is ready. See for integration with above.
-func setUpProxyWaitAlerts(clusterName, waitTask string, sess *session.Session) {
+func setUpProxyWaitAlerts(clusterName, waitTask, eip string, sess *session.Session) {
   fmt.Printf("%sWaiting for containers to be available before attaching to network. Updates will follow.%s\n", warnColor, resetColor)
   awslib.OnTaskRunning(clusterName, waitTask, sess,
     func(taskDecrip *ecs.DescribeTasksOutput, err error) {
       if err == nil {
         p, err := mclib.GetProxy(clusterName, waitTask, sess)
         if err == nil {
+          fillProxyAddress(p, clusterName, sess)
           fmt.Printf("\n%sProxy Task Running, server comming up.%s\n", titleColor, resetColor)
           w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
           fmt.Fprintf(w, "%sProxy\tProxy IP\tRcon IP\tTask%s\n", titleColor, resetColor)
@@ -64,6 +65,18 @@ func setUpProxyWaitAlerts(clusterName, waitTask string, sess *session.Session) {
           return
         }
 
+        fmt.Printf("%sWaiting for the proxy to accept connections ....%s\n", warnColor, resetColor)
+        if err = waitForProxyReady(p, clusterName, readyTimeoutArg, sess); err != nil {
+          fmt.Printf("%sProxy is %s. Not attaching proxy to network!%s\n", failColor, err, resetColor)
+          return
+        }
+
+        if p, err = withProxyEIP(p, clusterName, eip, sess); err != nil {
+          fmt.Printf("%sFailed to bind elastic IP %s: %s. Not attaching proxy to network!%s\n", failColor, eip, err, resetColor)
+          return
+        }
+        fillProxyAddress(p, clusterName, sess)
+
         fmt.Printf("%sAttaching to network ....%s", warnColor, resetColor)
         domainName, changeInfo, err := p.AttachToNetwork()
         if err == nil {
@@ -0,0 +1,281 @@
+package interactive
+
+import (
+  "fmt"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/autoscaling"
+  "github.com/aws/aws-sdk-go/service/ecs"
+)
+
+//
+// Pre-flight capacity checks.
+// Rather than find out from a RESOURCE:MEMORY or RESOURCE:PORTS failure,
+// see if some container instance has room for the task before launching it.
+// If none do, suggest growing the cluster's Auto Scaling group,
+// or with --scale grow it and wait for the new instance.
+//
+
+const (
+  defaultScaleTimeout = "10m"
+  scalePollInterval = 10 * time.Second
+)
+
+// What a task needs, or an instance has left.
+type resources struct {
+  CPU int64
+  Memory int64
+  Ports []string     // Host TCP ports.
+  UDPPorts []string  // Host UDP ports.
+}
+
+func (r resources) String() string {
+  s := fmt.Sprintf("%d CPU units, %d MiB", r.CPU, r.Memory)
+  if len(r.Ports) > 0 { s += ", tcp ports " + strings.Join(r.Ports, ",") }
+  if len(r.UDPPorts) > 0 { s += ", udp ports " + strings.Join(r.UDPPorts, ",") }
+  return s
+}
+
+// taskRequirements are the task level CPU and memory if set, otherwise the containers' sum,
+// and the fixed host ports. Dynamic host ports are always available, and awsvpc tasks have their own.
+func taskRequirements(td *ecs.TaskDefinition) (need resources) {
+  for _, cd := range td.ContainerDefinitions {
+    need.CPU += aws.Int64Value(cd.Cpu)
+    if cd.Memory != nil {
+      need.Memory += *cd.Memory
+    } else {
+      need.Memory += aws.Int64Value(cd.MemoryReservation)
+    }
+    for _, pm := range cd.PortMappings {
+      port := aws.Int64Value(pm.HostPort)
+      switch aws.StringValue(td.NetworkMode) {
+      case ecs.NetworkModeHost: port = aws.Int64Value(pm.ContainerPort)
+      case ecs.NetworkModeAwsvpc: port = 0
+      }
+      if port == 0 { continue }
+      if aws.StringValue(pm.Protocol) == ecs.TransportProtocolUdp {
+        need.UDPPorts = append(need.UDPPorts, strconv.FormatInt(port, 10))
+      } else {
+        need.Ports = append(need.Ports, strconv.FormatInt(port, 10))
+      }
+    }
+  }
+  if n, err := strconv.ParseInt(aws.StringValue(td.Cpu), 10, 64); err == nil { need.CPU = n }
+  if n, err := strconv.ParseInt(aws.StringValue(td.Memory), 10, 64); err == nil { need.Memory = n }
+  return need
+}
+
+// instanceRemaining is what's left on a container instance.
+func instanceRemaining(ci *ecs.ContainerInstance) (room resources) {
+  room.CPU = resourceValue(ci.RemainingResources, "CPU")
+  room.Memory = resourceValue(ci.RemainingResources, "MEMORY")
+  for _, r := range ci.RemainingResources {
+    switch aws.StringValue(r.Name) {
+    case "PORTS": room.Ports = aws.StringValueSlice(r.StringSetValue)
+    case "PORTS_UDP": room.UDPPorts = aws.StringValueSlice(r.StringSetValue)
+    }
+  }
+  return room
+}
+
+// fits says whether need fits in room, and if not why not.
+// Ports in room are the ones in use.
+func fits(need, room resources) (ok bool, reason string) {
+  if need.CPU > room.CPU { return false, fmt.Sprintf("needs %d CPU units, has %d", need.CPU, room.CPU) }
+  if need.Memory > room.Memory { return false, fmt.Sprintf("needs %d MiB, has %d", need.Memory, room.Memory) }
+  if p := firstShared(need.Ports, room.Ports); p != "" { return false, fmt.Sprintf("tcp port %s in use", p) }
+  if p := firstShared(need.UDPPorts, room.UDPPorts); p != "" { return false, fmt.Sprintf("udp port %s in use", p) }
+  return true, ""
+}
+
+func firstShared(a, b []string) string {
+  for _, x := range a {
+    for _, y := range b {
+      if x == y { return x }
+    }
+  }
+  return ""
+}
+
+// eligible says whether the placement allows the instance at all.
+// avoided are the container instances running tasks in the group to stay away from.
+func (pl placement) eligible(ci *ecs.ContainerInstance, avoided map[string]bool) bool {
+  if aws.StringValue(ci.Status) != ecs.ContainerInstanceStatusActive { return false }
+  if !pl.Colocate && avoided[aws.StringValue(ci.ContainerInstanceArn)] { return false }
+  if pl.Instance != "" && aws.StringValue(ci.Ec2InstanceId) != pl.Instance { return false }
+  if pl.AZ != "" && instanceAttribute(ci, "ecs.availability-zone") != pl.AZ { return false }
+  for _, a := range pl.Attributes {
+    kv := strings.SplitN(a, "=", 2)
+    if len(kv) == 2 && instanceAttribute(ci, kv[0]) != kv[1] { return false }
+  }
+  return true
+}
+
+func instanceAttribute(ci *ecs.ContainerInstance, name string) string {
+  for _, a := range ci.Attributes {
+    if aws.StringValue(a.Name) == name { return aws.StringValue(a.Value) }
+  }
+  return ""
+}
+
+func containerInstances(clusterName string, sess *session.Session) (cis []*ecs.ContainerInstance, err error) {
+  svc := ecs.New(sess)
+  arns := make([]*string, 0)
+  err = svc.ListContainerInstancesPages(&ecs.ListContainerInstancesInput{Cluster: aws.String(clusterName)},
+    func(resp *ecs.ListContainerInstancesOutput, last bool) bool {
+      arns = append(arns, resp.ContainerInstanceArns...)
+      return true
+    })
+  if err != nil || len(arns) == 0 { return cis, err }
+  // DescribeContainerInstances takes at most 100 at a time.
+  for i := 0; i < len(arns); i += 100 {
+    end := i + 100
+    if end > len(arns) { end = len(arns) }
+    resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+      Cluster: aws.String(clusterName),
+      ContainerInstances: arns[i:end],
+    })
+    if err != nil { return cis, err }
+    cis = append(cis, resp.ContainerInstances...)
+  }
+  return cis, nil
+}
+
+// groupInstances are the container instances running tasks in group, by arn.
+func groupInstances(clusterName, group string, sess *session.Session) (instances map[string]bool, err error) {
+  instances = make(map[string]bool)
+  if group == "" { return instances, nil }
+  tasks, err := describeTasksWithStatus(clusterName, ecs.DesiredStatusRunning, sess)
+  if err != nil { return instances, err }
+  for _, t := range tasks {
+    if aws.StringValue(t.Group) == group && t.ContainerInstanceArn != nil { instances[*t.ContainerInstanceArn] = true }
+  }
+  return instances, nil
+}
+
+// roomFor returns an instance with room for need, or nil and why each eligible one didn't fit.
+func roomFor(need resources, pl placement, cis []*ecs.ContainerInstance, avoided map[string]bool) (*ecs.ContainerInstance, []string) {
+  reasons := make([]string, 0)
+  for _, ci := range cis {
+    if !pl.eligible(ci, avoided) { continue }
+    ok, reason := fits(need, instanceRemaining(ci))
+    if ok { return ci, reasons }
+    reasons = append(reasons, fmt.Sprintf("%s: %s", aws.StringValue(ci.Ec2InstanceId), reason))
+  }
+  return nil, reasons
+}
+
+// ensureCapacity checks there is room on the cluster for the task definition, with placement pl,
+// away from the tasks in group avoid unless colocating.
+// If there isn't, with pl.Scale it grows the cluster's auto scaling group by one and waits for the new
+// instance, otherwise it says how to.
+func ensureCapacity(clusterName, taskDef, avoid string, pl placement, sess *session.Session) (error) {
+  td, err := getTaskDefinition(taskDef, sess)
+  if err != nil { return err }
+  // Fargate finds its own room.
+  if pl.fargate() { return checkFargateTaskDef(td) }
+  need := taskRequirements(td)
+  cis, err := containerInstances(clusterName, sess)
+  if err != nil { return fmt.Errorf("Failed to check capacity on %s: %s", clusterName, err) }
+  avoided, err := groupInstances(clusterName, avoid, sess)
+  if err != nil { return fmt.Errorf("Failed to find the %s tasks on %s: %s", avoid, clusterName, err) }
+  ci, reasons := roomFor(need, pl, cis, avoided)
+  if ci != nil {
+    fmt.Printf("%sRoom for %s on %s.%s\n", nullColor, taskID(*td.TaskDefinitionArn), aws.StringValue(ci.Ec2InstanceId), resetColor)
+    return nil
+  }
+
+  fmt.Printf("%sNo room on %s for %s, which needs %s.%s\n", warnColor, clusterName, taskID(*td.TaskDefinitionArn), need, resetColor)
+  for _, r := range reasons { fmt.Printf("%s  %s%s\n", nullColor, r, resetColor) }
+  if len(avoided) > 0 && !pl.Colocate {
+    fmt.Printf("%s  %d instances running %s tasks are out, --colocate allows them.%s\n", nullColor, len(avoided), avoid, resetColor)
+  }
+  if pl.Instance != "" { return fmt.Errorf("No room on instance %s.", pl.Instance) }
+
+  group, err := clusterScalingGroup(cis, sess)
+  if err != nil { return err }
+  desired := aws.Int64Value(group.DesiredCapacity) + 1
+  if desired > aws.Int64Value(group.MaxSize) {
+    return fmt.Errorf("Auto scaling group %s is already at its maximum of %d instances.", *group.AutoScalingGroupName, *group.MaxSize)
+  }
+  if !pl.Scale {
+    return fmt.Errorf("Use --scale to grow auto scaling group %s from %d to %d instances, then launch.",
+      *group.AutoScalingGroupName, *group.DesiredCapacity, desired)
+  }
+
+  _, err = autoscaling.New(sess).SetDesiredCapacity(&autoscaling.SetDesiredCapacityInput{
+    AutoScalingGroupName: group.AutoScalingGroupName,
+    DesiredCapacity: aws.Int64(desired),
+  })
+  if err != nil { return err }
+  fmt.Printf("%sGrowing %s to %d instances, waiting up to %s for the new one to join %s.%s\n", warnColor,
+    *group.AutoScalingGroupName, desired, pl.ScaleTimeout, clusterName, resetColor)
+  return waitForRoom(clusterName, need, avoid, pl, cis, sess)
+}
+
+// waitForRoom waits for a container instance that wasn't in before to register with room for need,
+// and no tasks in group avoid unless colocating.
+func waitForRoom(clusterName string, need resources, avoid string, pl placement, before []*ecs.ContainerInstance,
+  sess *session.Session) (error) {
+  known := make(map[string]bool)
+  for _, ci := range before { known[aws.StringValue(ci.ContainerInstanceArn)] = true }
+  start := time.Now()
+  for time.Since(start) < pl.ScaleTimeout {
+    time.Sleep(scalePollInterval)
+    cis, err := containerInstances(clusterName, sess)
+    if err != nil { return err }
+    added := make([]*ecs.ContainerInstance, 0)
+    for _, ci := range cis {
+      if !known[aws.StringValue(ci.ContainerInstanceArn)] { added = append(added, ci) }
+    }
+    if len(added) == 0 { continue }
+    avoided, err := groupInstances(clusterName, avoid, sess)
+    if err != nil { return err }
+    if ci, _ := roomFor(need, pl, added, avoided); ci != nil {
+      fmt.Printf("%s%s joined %s (%s).%s\n", successColor, aws.StringValue(ci.Ec2InstanceId), clusterName,
+        time.Since(start), resetColor)
+      return nil
+    }
+  }
+  return fmt.Errorf("No new instance with room joined %s after %s.", clusterName, pl.ScaleTimeout)
+}
+
+// clusterScalingGroup is the auto scaling group the cluster's instances belong to.
+// If they're spread across several, the one with the most of them.
+func clusterScalingGroup(cis []*ecs.ContainerInstance, sess *session.Session) (*autoscaling.Group, error) {
+  ids := make([]*string, 0)
+  for _, ci := range cis {
+    if ci.Ec2InstanceId != nil { ids = append(ids, ci.Ec2InstanceId) }
+  }
+  if len(ids) == 0 { return nil, fmt.Errorf("No instances in the cluster to find an auto scaling group from.") }
+
+  svc := autoscaling.New(sess)
+  counts := make(map[string]int)
+  // DescribeAutoScalingInstances takes at most 50 at a time.
+  for i := 0; i < len(ids); i += 50 {
+    end := i + 50
+    if end > len(ids) { end = len(ids) }
+    resp, err := svc.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{InstanceIds: ids[i:end]})
+    if err != nil { return nil, err }
+    for _, asi := range resp.AutoScalingInstances { counts[aws.StringValue(asi.AutoScalingGroupName)]++ }
+  }
+  if len(counts) == 0 { return nil, fmt.Errorf("The cluster's instances aren't in an auto scaling group.") }
+  names := make([]string, 0)
+  for n := range counts { names = append(names, n) }
+  sort.Strings(names)
+  name := names[0]
+  for _, n := range names {
+    if counts[n] > counts[name] { name = n }
+  }
+
+  resp, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+    AutoScalingGroupNames: []*string{aws.String(name)},
+  })
+  if err != nil { return nil, err }
+  if len(resp.AutoScalingGroups) == 0 { return nil, fmt.Errorf("No auto scaling group %s.", name) }
+  return resp.AutoScalingGroups[0], nil
+}
@@ -0,0 +1,84 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestTaskRequirements(t *testing.T) {
+  td := &ecs.TaskDefinition{
+    NetworkMode: aws.String(ecs.NetworkModeBridge),
+    ContainerDefinitions: []*ecs.ContainerDefinition{
+      {Cpu: aws.Int64(512), Memory: aws.Int64(2048), PortMappings: []*ecs.PortMapping{
+        {ContainerPort: aws.Int64(25565), HostPort: aws.Int64(25565)},
+        {ContainerPort: aws.Int64(25575), HostPort: aws.Int64(0)},
+        {ContainerPort: aws.Int64(19132), HostPort: aws.Int64(19132), Protocol: aws.String(ecs.TransportProtocolUdp)},
+      }},
+      {Cpu: aws.Int64(128), MemoryReservation: aws.Int64(256)},
+    },
+  }
+  need := taskRequirements(td)
+  assert.Equal(t, int64(640), need.CPU)
+  assert.Equal(t, int64(2304), need.Memory)
+  assert.Equal(t, []string{"25565"}, need.Ports)
+  assert.Equal(t, []string{"19132"}, need.UDPPorts)
+
+  td.NetworkMode = aws.String(ecs.NetworkModeHost)
+  td.Cpu = aws.String("1024")
+  td.Memory = aws.String("4096")
+  need = taskRequirements(td)
+  assert.Equal(t, int64(1024), need.CPU)
+  assert.Equal(t, int64(4096), need.Memory)
+  assert.Equal(t, []string{"25565", "25575"}, need.Ports)
+}
+
+func TestFits(t *testing.T) {
+  need := resources{CPU: 512, Memory: 2048, Ports: []string{"25565"}}
+  ok, _ := fits(need, resources{CPU: 1024, Memory: 4096, Ports: []string{"22", "25575"}})
+  assert.True(t, ok)
+  ok, reason := fits(need, resources{CPU: 256, Memory: 4096})
+  assert.False(t, ok)
+  assert.Contains(t, reason, "CPU")
+  ok, reason = fits(need, resources{CPU: 1024, Memory: 1024})
+  assert.False(t, ok)
+  assert.Contains(t, reason, "MiB")
+  ok, reason = fits(need, resources{CPU: 1024, Memory: 4096, Ports: []string{"25565"}})
+  assert.False(t, ok)
+  assert.Contains(t, reason, "25565")
+}
+
+func TestRoomFor(t *testing.T) {
+  instance := func(id, az string, mem int64) *ecs.ContainerInstance {
+    return &ecs.ContainerInstance{
+      ContainerInstanceArn: aws.String("arn-" + id),
+      Ec2InstanceId: aws.String(id),
+      Status: aws.String(ecs.ContainerInstanceStatusActive),
+      Attributes: []*ecs.Attribute{{Name: aws.String("ecs.availability-zone"), Value: aws.String(az)}},
+      RemainingResources: []*ecs.Resource{
+        {Name: aws.String("CPU"), IntegerValue: aws.Int64(2048)},
+        {Name: aws.String("MEMORY"), IntegerValue: aws.Int64(mem)},
+        {Name: aws.String("PORTS"), StringSetValue: aws.StringSlice([]string{"22"})},
+      },
+    }
+  }
+  cis := []*ecs.ContainerInstance{instance("i-small", "us-east-1a", 512), instance("i-big", "us-east-1b", 8192)}
+  need := resources{CPU: 1024, Memory: 4096}
+
+  ci, _ := roomFor(need, placement{}, cis, nil)
+  if assert.NotNil(t, ci) { assert.Equal(t, "i-big", *ci.Ec2InstanceId) }
+  ci, reasons := roomFor(need, placement{AZ: "us-east-1a"}, cis, nil)
+  assert.Nil(t, ci)
+  assert.Len(t, reasons, 1)
+  ci, reasons = roomFor(need, placement{Instance: "i-other"}, cis, nil)
+  assert.Nil(t, ci)
+  assert.Empty(t, reasons)
+
+  // Instances running the group to avoid are out, unless colocating.
+  avoided := map[string]bool{"arn-i-big": true}
+  ci, _ = roomFor(need, placement{}, cis, avoided)
+  assert.Nil(t, ci)
+  ci, _ = roomFor(need, placement{Colocate: true}, cis, avoided)
+  if assert.NotNil(t, ci) { assert.Equal(t, "i-big", *ci.Ec2InstanceId) }
+}
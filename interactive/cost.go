@@ -0,0 +1,327 @@
+package interactive
+
+import (
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/aws/aws-sdk-go/service/s3"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Cost reporting.
+// Task-hours for running and recently stopped tasks, each charged its share of
+// the instance it ran on: the larger of its CPU and memory reservation over what
+// the instance registered, times the instance type's hourly price.
+// Fargate tasks are charged by vCPU and GB hours. Archive storage is charged per
+// GB-month on the bucket listing, keys being user/server/...
+// ECS only keeps stopped tasks for about an hour, so older runs are missed.
+//
+
+const (
+  pricesFile = "./.ecs-craft_prices.json"
+  defaultCostSince = "30d"
+  costByUser = "user"
+  costByServer = "server"
+  costByCluster = "cluster"
+
+  hoursPerMonth = 730.0
+  bytesPerGB = 1 << 30
+)
+
+// Prices are USD, on-demand.
+type priceTable struct {
+  Instances map[string]float64   // Per hour, by instance type.
+  FargateVCPUHour float64
+  FargateGBHour float64
+  S3GBMonth float64
+}
+
+// us-east-1, for when there's no price file.
+var defaultPrices = priceTable{
+  Instances: map[string]float64{
+    "t2.small": 0.023,
+    "t2.medium": 0.0464,
+    "t2.large": 0.0928,
+    "t3.medium": 0.0416,
+    "t3.large": 0.0832,
+    "m4.large": 0.10,
+    "m5.large": 0.096,
+    "m5.xlarge": 0.192,
+    "c5.large": 0.085,
+    "c5.xlarge": 0.17,
+    "r5.large": 0.126,
+  },
+  FargateVCPUHour: 0.04048,
+  FargateGBHour: 0.004445,
+  S3GBMonth: 0.023,
+}
+
+// loadPriceTable reads the price table, falling back to the defaults for anything not set.
+func loadPriceTable(fileName string) (pt priceTable, err error) {
+  pt = defaultPrices
+  data, err := ioutil.ReadFile(fileName)
+  if os.IsNotExist(err) { return pt, nil }
+  if err != nil { return pt, err }
+  var file priceTable
+  if err = json.Unmarshal(data, &file); err != nil { return pt, fmt.Errorf("Bad price table %s: %s", fileName, err) }
+  instances := make(map[string]float64)
+  for t, p := range defaultPrices.Instances { instances[t] = p }
+  for t, p := range file.Instances { instances[t] = p }
+  pt.Instances = instances
+  if file.FargateVCPUHour > 0 { pt.FargateVCPUHour = file.FargateVCPUHour }
+  if file.FargateGBHour > 0 { pt.FargateGBHour = file.FargateGBHour }
+  if file.S3GBMonth > 0 { pt.S3GBMonth = file.S3GBMonth }
+  return pt, nil
+}
+
+// parseSince takes a duration, with d for days as well.
+func parseSince(since string) (time.Duration, error) {
+  if strings.HasSuffix(since, "d") {
+    days, err := strconv.ParseFloat(strings.TrimSuffix(since, "d"), 64)
+    if err != nil || days <= 0 { return 0, fmt.Errorf("Bad --since %q, try 30d or 12h.", since) }
+    return time.Duration(days * float64(24 * time.Hour)), nil
+  }
+  d, err := time.ParseDuration(since)
+  if err != nil || d <= 0 { return 0, fmt.Errorf("Bad --since %q, try 30d or 12h.", since) }
+  return d, nil
+}
+
+// taskHours is how long the task ran between from and now.
+func taskHours(t *ecs.Task, from, now time.Time) float64 {
+  if t.StartedAt == nil { return 0 }
+  start, end := *t.StartedAt, now
+  if t.StoppedAt != nil { end = *t.StoppedAt }
+  if start.Before(from) { start = from }
+  if !end.After(start) { return 0 }
+  return end.Sub(start).Hours()
+}
+
+// instanceShare is the part of an instance the task holds, by whichever of CPU and memory it takes more of.
+func instanceShare(need, registered resources) float64 {
+  share := 0.0
+  if registered.CPU > 0 { share = float64(need.CPU) / float64(registered.CPU) }
+  if registered.Memory > 0 {
+    if m := float64(need.Memory) / float64(registered.Memory); m > share { share = m }
+  }
+  if share > 1 { share = 1 }
+  return share
+}
+
+// fargateHourly is the hourly price of a Fargate task of the given size.
+func (pt priceTable) fargateHourly(need resources) float64 {
+  return float64(need.CPU) / 1024 * pt.FargateVCPUHour + float64(need.Memory) / 1024 * pt.FargateGBHour
+}
+
+// What we know about an instance to price the tasks on it.
+type pricedInstance struct {
+  Type string
+  Registered resources
+}
+
+// costLine is what's been spent by one user, server or cluster.
+type costLine struct {
+  Key string
+  Tasks int
+  Hours float64
+  Compute float64
+  UnpricedHours float64   // Hours on instances we couldn't price.
+  ArchiveBytes int64
+  Storage float64
+}
+
+func (cl *costLine) total() float64 { return cl.Compute + cl.Storage }
+
+type costReport map[string]*costLine
+
+func (cr costReport) line(key string) (*costLine) {
+  cl, ok := cr[key]
+  if !ok {
+    cl = &costLine{Key: key}
+    cr[key] = cl
+  }
+  return cl
+}
+
+// costKey is the report line a task, or archive, goes on.
+func costKey(by, clusterName, user, server string) string {
+  if user == "" { user = "(proxy)" }
+  switch by {
+  case costByServer: return user + "/" + server
+  case costByCluster: return clusterName
+  }
+  return user
+}
+
+// archiveOwner splits an archive key into user and server.
+func archiveOwner(key string) (user, server string) {
+  parts := strings.SplitN(key, "/", 3)
+  if len(parts) < 2 { return parts[0], "" }
+  return parts[0], parts[1]
+}
+
+func doCostReportCmd(sess *session.Session) (error) {
+  since, err := parseSince(costSinceArg)
+  if err != nil { return err }
+  pt, err := loadPriceTable(costPricesArg)
+  if err != nil { return err }
+  now := time.Now()
+  from := now.Add(-since)
+
+  clusters, err := awslib.GetAllClusterDescriptions(sess)
+  if err != nil { return err }
+  cr := make(costReport)
+  tds := make(map[string]*ecs.TaskDefinition)
+  for _, c := range clusters {
+    if err = addClusterCosts(cr, *c.ClusterName, costByArg, pt, from, now, tds, sess); err != nil {
+      fmt.Printf("%sSkipping cluster %s: %s%s\n", warnColor, *c.ClusterName, err, resetColor)
+    }
+  }
+  if err = addArchiveCosts(cr, costBucketArg, costByArg, pt, since, sess); err != nil {
+    fmt.Printf("%sSkipping archive storage in %s: %s%s\n", warnColor, costBucketArg, err, resetColor)
+  }
+  printCostReport(cr, from, now)
+  return nil
+}
+
+// addClusterCosts charges each running and recently stopped task on the cluster to its line.
+func addClusterCosts(cr costReport, clusterName, by string, pt priceTable, from, now time.Time,
+  tds map[string]*ecs.TaskDefinition, sess *session.Session) (error) {
+  instances := make(map[string]pricedInstance)
+  cis, err := containerInstances(clusterName, sess)
+  if err != nil { return err }
+  for _, ci := range cis {
+    instances[aws.StringValue(ci.ContainerInstanceArn)] = pricedInstance{
+      Type: instanceAttribute(ci, "ecs.instance-type"),
+      Registered: resources{
+        CPU: resourceValue(ci.RegisteredResources, "CPU"),
+        Memory: resourceValue(ci.RegisteredResources, "MEMORY"),
+      },
+    }
+  }
+
+  tasks := make([]*ecs.Task, 0)
+  for _, status := range []string{ecs.DesiredStatusRunning, ecs.DesiredStatusStopped} {
+    ts, err := describeTasksWithStatus(clusterName, status, sess)
+    if err != nil { return err }
+    tasks = append(tasks, ts...)
+  }
+  for _, t := range tasks {
+    hours := taskHours(t, from, now)
+    if hours == 0 { continue }
+    tdArn := aws.StringValue(t.TaskDefinitionArn)
+    td, ok := tds[tdArn]
+    if !ok {
+      if td, err = getTaskDefinition(tdArn, sess); err != nil { return err }
+      tds[tdArn] = td
+    }
+    need := taskRequirements(td)
+
+    cl := cr.line(costKey(by, clusterName, taskEnvValue(t, mclib.ServerUserKey), taskEnvValue(t, mclib.ServerNameKey)))
+    cl.Tasks++
+    cl.Hours += hours
+    if aws.StringValue(t.LaunchType) == ecs.LaunchTypeFargate {
+      cl.Compute += pt.fargateHourly(need) * hours
+      continue
+    }
+    pi, ok := instances[aws.StringValue(t.ContainerInstanceArn)]
+    price, priced := pt.Instances[pi.Type]
+    if !ok || !priced {
+      cl.UnpricedHours += hours
+      continue
+    }
+    cl.Compute += instanceShare(need, pi.Registered) * price * hours
+  }
+  return nil
+}
+
+// addArchiveCosts charges the archive storage in the bucket to its owners, for the length of the report.
+// By cluster it all goes on one line, archives aren't kept by cluster.
+func addArchiveCosts(cr costReport, bucket, by string, pt priceTable, since time.Duration, sess *session.Session) (error) {
+  sizes, err := archiveSizes(bucket, "", sess)
+  if err != nil { return err }
+  months := since.Hours() / hoursPerMonth
+  for key, size := range sizes {
+    user, server := archiveOwner(key)
+    lineKey := costKey(by, "", user, server)
+    if by == costByCluster { lineKey = "(archives)" }
+    cl := cr.line(lineKey)
+    cl.ArchiveBytes += size
+    cl.Storage += float64(size) / bytesPerGB * pt.S3GBMonth * months
+  }
+  return nil
+}
+
+// archiveSizes is the size of everything in the bucket under prefix, by key.
+func archiveSizes(bucket, prefix string, sess *session.Session) (sizes map[string]int64, err error) {
+  sizes = make(map[string]int64)
+  err = s3.New(sess).ListObjectsV2Pages(&s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)},
+    func(page *s3.ListObjectsV2Output, last bool) bool {
+      for _, o := range page.Contents { sizes[aws.StringValue(o.Key)] = aws.Int64Value(o.Size) }
+      return true
+    })
+  return sizes, err
+}
+
+// byCost sorts the most expensive first.
+type byCost []*costLine
+func (c byCost) Len() int { return len(c) }
+func (c byCost) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byCost) Less(i, j int) bool {
+  if c[i].total() != c[j].total() { return c[i].total() > c[j].total() }
+  return c[i].Key < c[j].Key
+}
+
+func printCostReport(cr costReport, from, now time.Time) {
+  lines := make([]*costLine, 0)
+  for _, cl := range cr { lines = append(lines, cl) }
+  sort.Sort(byCost(lines))
+
+  fmt.Printf("%sCosts by %s from %s to %s%s\n", titleColor, costByArg,
+    from.Local().Format(time.RFC1123), now.Local().Format(time.RFC1123), resetColor)
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%s%s\tTasks\tTask Hours\tCompute\tArchive GB\tStorage\tTotal%s\n", titleColor, strings.Title(costByArg), resetColor)
+  if len(lines) == 0 {
+    fmt.Fprintf(w, "%s\tNO TASKS OR ARCHIVES FOUND%s\n", titleColor, resetColor)
+    w.Flush()
+    return
+  }
+  var total costLine
+  unpriced := false
+  for _, cl := range lines {
+    color := nullColor
+    hours := fmt.Sprintf("%.1f", cl.Hours)
+    if cl.UnpricedHours > 0 {
+      color = warnColor
+      hours = fmt.Sprintf("%s (%.1f unpriced)", hours, cl.UnpricedHours)
+      unpriced = true
+    }
+    fmt.Fprintf(w, "%s%s\t%d\t%s\t$%.2f\t%.2f\t$%.2f\t$%.2f%s\n", color, cl.Key, cl.Tasks, hours,
+      cl.Compute, float64(cl.ArchiveBytes) / bytesPerGB, cl.Storage, cl.total(), resetColor)
+    total.Tasks += cl.Tasks
+    total.Hours += cl.Hours
+    total.Compute += cl.Compute
+    total.ArchiveBytes += cl.ArchiveBytes
+    total.Storage += cl.Storage
+  }
+  fmt.Fprintf(w, "%sTotal\t%d\t%.1f\t$%.2f\t%.2f\t$%.2f\t$%.2f%s\n", titleColor, total.Tasks, total.Hours,
+    total.Compute, float64(total.ArchiveBytes) / bytesPerGB, total.Storage, total.total(), resetColor)
+  w.Flush()
+  if unpriced {
+    fmt.Printf("%sSome instance types aren't in the price table, add them to %s.%s\n", warnColor, costPricesArg, resetColor)
+  }
+}
@@ -0,0 +1,79 @@
+package interactive
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestParseSince(t *testing.T) {
+  d, err := parseSince("30d")
+  assert.NoError(t, err)
+  assert.Equal(t, 30 * 24 * time.Hour, d)
+  d, err = parseSince("12h")
+  assert.NoError(t, err)
+  assert.Equal(t, 12 * time.Hour, d)
+  _, err = parseSince("soon")
+  assert.Error(t, err)
+  _, err = parseSince("-1d")
+  assert.Error(t, err)
+}
+
+func TestTaskHours(t *testing.T) {
+  now := time.Date(2017, 3, 10, 12, 0, 0, 0, time.UTC)
+  from := now.Add(-24 * time.Hour)
+  running := &ecs.Task{StartedAt: aws.Time(now.Add(-2 * time.Hour))}
+  assert.InDelta(t, 2.0, taskHours(running, from, now), 0.001)
+  old := &ecs.Task{StartedAt: aws.Time(now.Add(-48 * time.Hour))}
+  assert.InDelta(t, 24.0, taskHours(old, from, now), 0.001)
+  stopped := &ecs.Task{StartedAt: aws.Time(now.Add(-3 * time.Hour)), StoppedAt: aws.Time(now.Add(-1 * time.Hour))}
+  assert.InDelta(t, 2.0, taskHours(stopped, from, now), 0.001)
+  gone := &ecs.Task{StartedAt: aws.Time(now.Add(-50 * time.Hour)), StoppedAt: aws.Time(now.Add(-30 * time.Hour))}
+  assert.Equal(t, 0.0, taskHours(gone, from, now))
+  assert.Equal(t, 0.0, taskHours(&ecs.Task{}, from, now))
+}
+
+func TestInstanceShare(t *testing.T) {
+  registered := resources{CPU: 2048, Memory: 8192}
+  assert.InDelta(t, 0.5, instanceShare(resources{CPU: 512, Memory: 4096}, registered), 0.001)
+  assert.InDelta(t, 0.5, instanceShare(resources{CPU: 1024, Memory: 1024}, registered), 0.001)
+  assert.Equal(t, 1.0, instanceShare(resources{CPU: 4096}, registered))
+}
+
+func TestCostKey(t *testing.T) {
+  assert.Equal(t, "jdr", costKey(costByUser, "craft", "jdr", "world"))
+  assert.Equal(t, "jdr/world", costKey(costByServer, "craft", "jdr", "world"))
+  assert.Equal(t, "craft", costKey(costByCluster, "craft", "jdr", "world"))
+  assert.Equal(t, "(proxy)", costKey(costByUser, "craft", "", "hub"))
+
+  user, server := archiveOwner("jdr/world/snapshots/2017-03-01.zip")
+  assert.Equal(t, "jdr", user)
+  assert.Equal(t, "world", server)
+}
+
+func TestLoadPriceTable(t *testing.T) {
+  dir, err := ioutil.TempDir("", "prices")
+  assert.NoError(t, err)
+  defer os.RemoveAll(dir)
+  fileName := filepath.Join(dir, "prices.json")
+
+  pt, err := loadPriceTable(fileName)
+  assert.NoError(t, err)
+  assert.Equal(t, defaultPrices.S3GBMonth, pt.S3GBMonth)
+
+  err = ioutil.WriteFile(fileName, []byte(`{"Instances": {"t2.medium": 0.05, "x1.huge": 9}, "S3GBMonth": 0.02}`), 0600)
+  assert.NoError(t, err)
+  pt, err = loadPriceTable(fileName)
+  assert.NoError(t, err)
+  assert.Equal(t, 0.05, pt.Instances["t2.medium"])
+  assert.Equal(t, 9.0, pt.Instances["x1.huge"])
+  assert.Equal(t, defaultPrices.Instances["m5.large"], pt.Instances["m5.large"])
+  assert.Equal(t, 0.02, pt.S3GBMonth)
+  assert.Equal(t, defaultPrices.FargateVCPUHour, pt.FargateVCPUHour)
+  assert.Equal(t, 0.0464, defaultPrices.Instances["t2.medium"], "defaults left alone")
+}
@@ -0,0 +1,116 @@
+package interactive
+
+import (
+  "fmt"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ec2"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Elastic IPs for proxies.
+// The address is associated with the proxy's container instance, so the proxy's
+// DNS stays put when the proxy is replaced. Note this changes the public
+// address of everything else on that instance too.
+// With "auto" we reuse the address tagged for the proxy, or allocate and tag one.
+//
+
+const (
+  eipAuto = "auto"
+  eipProxyTag = "ecs-craft:proxy"
+)
+
+func proxyEIPTagValue(clusterName, proxyName string) string {
+  return clusterName + "/" + proxyName
+}
+
+// proxyInstanceID is the EC2 instance the proxy's task is running on.
+func proxyInstanceID(p *mclib.Proxy, clusterName string, sess *session.Session) (string, error) {
+  task, err := describeTask(clusterName, p.TaskArn, sess)
+  if err != nil { return "", err }
+  if task.ContainerInstanceArn == nil { return "", fmt.Errorf("Proxy %s isn't on a container instance.", p.Name) }
+  ci, err := describeContainerInstance(clusterName, *task.ContainerInstanceArn, sess)
+  if err != nil { return "", err }
+  if ci.Ec2InstanceId == nil { return "", fmt.Errorf("No EC2 instance for proxy %s.", p.Name) }
+  return *ci.Ec2InstanceId, nil
+}
+
+// bindProxyEIP associates the address, an allocation id or auto, with the proxy's instance.
+// Returns the public IP.
+func bindProxyEIP(p *mclib.Proxy, clusterName, eip string, sess *session.Session) (ip string, err error) {
+  svc := ec2.New(sess)
+  instanceID, err := proxyInstanceID(p, clusterName, sess)
+  if err != nil { return ip, err }
+
+  var address *ec2.Address
+  if eip == eipAuto {
+    address, err = taggedProxyAddress(svc, clusterName, p.Name)
+    if err != nil { return ip, err }
+    if address == nil {
+      if address, err = allocateProxyAddress(svc, clusterName, p.Name); err != nil { return ip, err }
+      fmt.Printf("%sAllocated %s (%s) for %s.%s\n", successColor, aws.StringValue(address.PublicIp),
+        aws.StringValue(address.AllocationId), p.Name, resetColor)
+    }
+  } else {
+    resp, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{AllocationIds: []*string{aws.String(eip)}})
+    if err != nil { return ip, err }
+    if len(resp.Addresses) == 0 { return ip, fmt.Errorf("No elastic IP %s.", eip) }
+    address = resp.Addresses[0]
+  }
+
+  if address.InstanceId != nil && *address.InstanceId == instanceID { return aws.StringValue(address.PublicIp), nil }
+  _, err = svc.AssociateAddress(&ec2.AssociateAddressInput{
+    AllocationId: address.AllocationId,
+    InstanceId: aws.String(instanceID),
+    AllowReassociation: aws.Bool(true),
+  })
+  if err != nil { return ip, err }
+  fmt.Printf("%s%s now on %s for %s.%s\n", successColor, aws.StringValue(address.PublicIp), instanceID, p.Name, resetColor)
+  return aws.StringValue(address.PublicIp), nil
+}
+
+func taggedProxyAddress(svc *ec2.EC2, clusterName, proxyName string) (*ec2.Address, error) {
+  resp, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+    Filters: []*ec2.Filter{{
+      Name: aws.String("tag:" + eipProxyTag),
+      Values: []*string{aws.String(proxyEIPTagValue(clusterName, proxyName))},
+    }},
+  })
+  if err != nil { return nil, err }
+  if len(resp.Addresses) == 0 { return nil, nil }
+  return resp.Addresses[0], nil
+}
+
+func allocateProxyAddress(svc *ec2.EC2, clusterName, proxyName string) (*ec2.Address, error) {
+  resp, err := svc.AllocateAddress(&ec2.AllocateAddressInput{Domain: aws.String(ec2.DomainTypeVpc)})
+  if err != nil { return nil, err }
+  _, err = svc.CreateTags(&ec2.CreateTagsInput{
+    Resources: []*string{resp.AllocationId},
+    Tags: []*ec2.Tag{{Key: aws.String(eipProxyTag), Value: aws.String(proxyEIPTagValue(clusterName, proxyName))}},
+  })
+  if err != nil { return nil, fmt.Errorf("Allocated %s, but failed to tag it: %s", *resp.AllocationId, err) }
+  return &ec2.Address{AllocationId: resp.AllocationId, PublicIp: resp.PublicIp}, nil
+}
+
+// instanceEIPs maps instance ids to their elastic IPs.
+func instanceEIPs(sess *session.Session) (map[string]*ec2.Address, error) {
+  resp, err := ec2.New(sess).DescribeAddresses(&ec2.DescribeAddressesInput{})
+  if err != nil { return nil, err }
+  eips := make(map[string]*ec2.Address)
+  for _, a := range resp.Addresses {
+    if a.InstanceId != nil { eips[*a.InstanceId] = a }
+  }
+  return eips, nil
+}
+
+// withProxyEIP binds the elastic IP, if there is one, and returns
+// the proxy with its new public address.
+func withProxyEIP(p *mclib.Proxy, clusterName, eip string, sess *session.Session) (*mclib.Proxy, error) {
+  if eip == "" { return p, nil }
+  if _, err := bindProxyEIP(p, clusterName, eip, sess); err != nil { return p, err }
+  // Pick up the new public address.
+  return mclib.GetProxy(clusterName, p.TaskArn, sess)
+}
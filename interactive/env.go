@@ -2,21 +2,142 @@ package interactive
 
 import(
   "fmt"
+  "os"
+  "text/tabwriter"
   "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
 
   // "mclib"
   "github.com/jdrivas/mclib"
 )
 
+// Container name -> environment.
+type containerEnvs map[string]map[string]string
+
 func doListEnv(sess *session.Session) (error) {
-  s, err := mclib.GetServerFromName(serverNameArg, currentCluster, sess)
+  envs, _, err := namedTaskEnvs(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+
+  containers := sortedContainers(envs)
+  if !envAllFlag {
+    container := envContainerArg
+    if container == "" { container = defaultEnvContainer(envs) }
+    if _, ok := envs[container]; !ok {
+      return fmt.Errorf("No container %s for %s, try one of: %v", container, serverNameArg, containers)
+    }
+    containers = []string{container}
+  }
+
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sContainer\tKey\tValue%s\n", titleColor, resetColor)
+  for _, c := range containers {
+    env := envs[c]
+    for _, k := range sortedFlatKeys(env) {
+      fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", nullColor, c, k, redactValue(k, env[k]), resetColor)
+    }
+  }
+  w.Flush()
+  return nil
+}
+
+func doDiffEnv(sess *session.Session) (error) {
+  from, td, err := namedTaskEnvs(serverNameArg, currentCluster, sess)
   if err != nil { return err }
 
-  env, ok := s.ServerEnvironment() 
-  if !ok {return fmt.Errorf("Failed to find server environment for: %s:%s", currentCluster, serverNameArg)}
+  var to containerEnvs
+  fromName, toName := serverNameArg, otherServerNameArg
+  switch {
+  case envAgainstTaskDefFlag:
+    // What the task definition would give you, against what this server got.
+    to = from
+    from = taskDefEnvs(td)
+    fromName, toName = taskID(*td.TaskDefinitionArn), serverNameArg
+  case otherServerNameArg != "":
+    to, _, err = namedTaskEnvs(otherServerNameArg, currentCluster, sess)
+    if err != nil { return err }
+  default:
+    return fmt.Errorf("Need another server to compare with, or --against-taskdef.")
+  }
 
-  for k, v := range env {
-    fmt.Printf("[%s] = %s\n", k, v)
+  diffs := diffFlat(flattenEnvs(from), flattenEnvs(to))
+  if len(diffs) == 0 {
+    fmt.Printf("%sNo differences between %s and %s.%s\n", successColor, fromName, toName, resetColor)
+    return nil
+  }
+  for i, d := range diffs {
+    diffs[i].From = redactValue(d.Key, d.From)
+    diffs[i].To = redactValue(d.Key, d.To)
   }
+  fmt.Printf("%sFrom %s to %s:%s\n", titleColor, fromName, toName, resetColor)
+  printFlatDiffs(diffs, "Container.Key")
   return nil
-}
\ No newline at end of file
+}
+
+// namedTaskEnvs finds the effective container environments for a server,
+// or failing that a proxy, by name.
+func namedTaskEnvs(name, clusterName string, sess *session.Session) (envs containerEnvs, td *ecs.TaskDefinition, err error) {
+  if s, serr := getServer(name, clusterName, sess); serr == nil {
+    td = s.DeepTask.TaskDefinition
+    return taskEnvs(s.DeepTask.Task, td), td, nil
+  }
+  p, err := mclib.GetProxyFromName(name, clusterName, sess)
+  if err != nil { return envs, td, fmt.Errorf("No server or proxy named %s on %s.", name, clusterName) }
+  task, err := describeTask(clusterName, p.TaskArn, sess)
+  if err != nil { return envs, td, err }
+  td, err = getTaskDefinition(*task.TaskDefinitionArn, sess)
+  if err != nil { return envs, td, err }
+  return taskEnvs(task, td), td, nil
+}
+
+// taskDefEnvs are the environments as set in the task definition.
+func taskDefEnvs(td *ecs.TaskDefinition) (containerEnvs) {
+  envs := make(containerEnvs)
+  for _, cd := range td.ContainerDefinitions {
+    env := make(map[string]string)
+    for _, kv := range cd.Environment {
+      if kv.Name != nil && kv.Value != nil { env[*kv.Name] = *kv.Value }
+    }
+    envs[*cd.Name] = env
+  }
+  return envs
+}
+
+// taskEnvs are the task definition's environments with the task's overrides on top,
+// which is what the containers actually see.
+func taskEnvs(task *ecs.Task, td *ecs.TaskDefinition) (containerEnvs) {
+  envs := taskDefEnvs(td)
+  if task.Overrides == nil { return envs }
+  for _, co := range task.Overrides.ContainerOverrides {
+    if co.Name == nil { continue }
+    env, ok := envs[*co.Name]
+    if !ok {
+      env = make(map[string]string)
+      envs[*co.Name] = env
+    }
+    for _, kv := range co.Environment {
+      if kv.Name != nil && kv.Value != nil { env[*kv.Name] = *kv.Value }
+    }
+  }
+  return envs
+}
+
+// Minecraft for servers, bungee for proxies.
+func defaultEnvContainer(envs containerEnvs) string {
+  if _, ok := envs[serverContainerName]; ok { return serverContainerName }
+  return mclib.BungeeProxyServerContainerName
+}
+
+func sortedContainers(envs containerEnvs) (s []string) {
+  m := make(map[string]string)
+  for c := range envs { m[c] = c }
+  return sortedFlatKeys(m)
+}
+
+// flattenEnvs keys on container.KEY for diffing.
+func flattenEnvs(envs containerEnvs) (map[string]string) {
+  flat := make(map[string]string)
+  for c, env := range envs {
+    for k, v := range env { flat[c + "." + k] = v }
+  }
+  return flat
+}
@@ -0,0 +1,52 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestTaskEnvs(t *testing.T) {
+  td := &ecs.TaskDefinition{
+    ContainerDefinitions: []*ecs.ContainerDefinition{
+      {
+        Name: aws.String("minecraft"),
+        Environment: []*ecs.KeyValuePair{
+          {Name: aws.String("MOTD"), Value: aws.String("default")},
+          {Name: aws.String("PVP"), Value: aws.String("true")},
+        },
+      },
+      {Name: aws.String("controller")},
+    },
+  }
+  task := &ecs.Task{
+    Overrides: &ecs.TaskOverride{
+      ContainerOverrides: []*ecs.ContainerOverride{
+        {
+          Name: aws.String("minecraft"),
+          Environment: []*ecs.KeyValuePair{{Name: aws.String("MOTD"), Value: aws.String("mine")}},
+        },
+        {
+          Name: aws.String("controller"),
+          Environment: []*ecs.KeyValuePair{{Name: aws.String("SERVER_NAME"), Value: aws.String("world")}},
+        },
+      },
+    },
+  }
+
+  envs := taskEnvs(task, td)
+  assert.Equal(t, containerEnvs{
+    "minecraft": {"MOTD": "mine", "PVP": "true"},
+    "controller": {"SERVER_NAME": "world"},
+  }, envs)
+  assert.Equal(t, "default", taskDefEnvs(td)["minecraft"]["MOTD"])
+  assert.Equal(t, []string{"controller", "minecraft"}, sortedContainers(envs))
+  assert.Equal(t, "minecraft", defaultEnvContainer(envs))
+
+  diffs := diffFlat(flattenEnvs(taskDefEnvs(td)), flattenEnvs(envs))
+  assert.Equal(t, []flatDiff{
+    {Key: "controller.SERVER_NAME", From: "", To: "world"},
+    {Key: "minecraft.MOTD", From: "default", To: "mine"},
+  }, diffs)
+}
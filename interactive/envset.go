@@ -0,0 +1,115 @@
+package interactive
+
+import (
+  "fmt"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Changing a running server's environment.
+// Task environments can't change, so this saves the world and restarts
+// the server onto a new task with the new settings, from a snapshot.
+// The controller archives snapshots on its own schedule and there's no way
+// to ask it for one now, so after the save we wait for its next one.
+// --snapshot restarts from a given snapshot instead, and --no-save from
+// the latest one, losing anything since.
+//
+
+const (
+  defaultSnapshotTimeout = "15m"
+  snapshotPollInterval = 15 * time.Second
+)
+
+func doEnvSetCmd(sess *session.Session) (error) {
+  s, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+
+  changes, err := parseSettings(envSetArg, "")
+  if err != nil { return err }
+  current, ok := s.ServerEnvironment()
+  if !ok { return fmt.Errorf("Failed to find server environment for: %s:%s", currentCluster, serverNameArg) }
+
+  diffs := diffFlat(current, mergeSettings(current, changes))
+  if len(diffs) == 0 {
+    fmt.Printf("%s%s already has those settings.%s\n", successColor, s.Name, resetColor)
+    return nil
+  }
+  for i, d := range diffs {
+    diffs[i].From = redactValue(d.Key, d.From)
+    diffs[i].To = redactValue(d.Key, d.To)
+  }
+  fmt.Printf("%sChanges to %s:%s\n", titleColor, s.Name, resetColor)
+  printFlatDiffs(diffs, "Key")
+  if envDryRunFlag { return nil }
+
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+
+  snapshot := envSnapshotArg
+  switch {
+  case snapshot != "":
+  case envNoSaveFlag:
+    snapshot, err = latestSnapshot(s)
+  default:
+    snapshot, err = saveAndSnapshot(s, snapshotTimeoutArg)
+    if err != nil { err = fmt.Errorf("%s Server not restarted, use --snapshot or --no-save to restart anyway.", err) }
+  }
+  if err != nil { return err }
+
+  nServer, err := restartServer(restartSpec{
+    Server: s,
+    Proxy: serverProxy(s, proxies),
+    Snapshot: snapshot,
+    Env: changes,
+    TaskDef: *s.DeepTask.TaskDefinition.TaskDefinitionArn,
+    Cluster: currentCluster,
+    ReadyTimeout: readyTimeoutArg,
+  }, sess)
+  if err != nil { return err }
+  fmt.Printf("%s%s restarted with the new settings on %s.%s\n", successColor, nServer.Name,
+    taskID(*nServer.TaskArn), resetColor)
+  return nil
+}
+
+// saveWorld gets the server to write the world to disk, for the controller's next snapshot.
+func saveWorld(s *mclib.Server) (error) {
+  resp, err := serverRconCommand(s, "save-all flush")
+  if err != nil { return fmt.Errorf("Failed to save the world on %s: %s.", s.Name, err) }
+  fmt.Printf("%s%s: %s%s\n", nullColor, s.Name, formattingCodes.ReplaceAllString(resp, ""), resetColor)
+  return nil
+}
+
+// saveAndSnapshot saves the world and waits up to timeout for the controller
+// to archive a snapshot taken after the save.
+func saveAndSnapshot(s *mclib.Server, timeout time.Duration) (uri string, err error) {
+  saved := time.Now()
+  if err = saveWorld(s); err != nil { return uri, err }
+  fmt.Printf("%sWaiting up to %s for a snapshot of %s.%s\n", warnColor, timeout, s.Name, resetColor)
+  for time.Since(saved) < timeout {
+    bu, err := s.LatestServerSnapshot()
+    if err == nil && bu.LastMod().After(saved) {
+      fmt.Printf("%sUsing snapshot %s taken at %s.%s\n", successColor, bu.URI(),
+        bu.LastMod().Local().Format(time.RFC1123), resetColor)
+      return bu.URI(), nil
+    }
+    if err != nil { log.Debug(fmt.Sprintf("Failed to find the latest snapshot of %s: %s", s.Name, err)) }
+    time.Sleep(snapshotPollInterval)
+  }
+  return uri, fmt.Errorf("No snapshot of %s newer than the save after %s.", s.Name, timeout)
+}
+
+// latestSnapshot is the URI of the server's latest archived snapshot, saying how old it is.
+func latestSnapshot(s *mclib.Server) (uri string, err error) {
+  bu, err := s.LatestServerSnapshot()
+  if err != nil { return uri, fmt.Errorf("Failed to find the latest snapshot of %s: %s", s.Name, err) }
+  fmt.Printf("%sUsing snapshot %s taken at %s (%s ago), anything since is lost.%s\n", warnColor, bu.URI(),
+    bu.LastMod().Local().Format(time.RFC1123), awslib.ShortDurationString(time.Since(bu.LastMod())), resetColor)
+  return bu.URI(), nil
+}
@@ -0,0 +1,167 @@
+package interactive
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ec2"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Fargate.
+// Fargate tasks have no container instance, they get their own ENI (awsvpc networking),
+// so the addresses mclib reads from the instance are empty and we look them up on the ENI.
+// Container ports are the host ports.
+//
+
+const (
+  launchTypeEC2 = "ec2"
+  launchTypeFargate = "fargate"
+
+  eniAttachmentType = "ElasticNetworkInterface"
+  defaultRconPort = "25575"
+)
+
+func (pl placement) fargate() bool { return pl.LaunchType == launchTypeFargate }
+
+// validateFargate checks the placement makes sense for Fargate, which places tasks itself.
+func (pl placement) validateFargate() (error) {
+  if pl.Instance != "" || pl.AZ != "" || len(pl.Attributes) > 0 || pl.Strategy != "" {
+    return fmt.Errorf("Fargate places tasks itself, --instance, --az, --attribute, --spread and --binpack don't apply.")
+  }
+  if len(pl.Subnets) == 0 { return fmt.Errorf("Fargate needs at least one --subnet.") }
+  return nil
+}
+
+func (pl placement) networkConfiguration() (*ecs.NetworkConfiguration) {
+  return &ecs.NetworkConfiguration{
+    AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+      Subnets: aws.StringSlice(pl.Subnets),
+      SecurityGroups: aws.StringSlice(pl.SecurityGroups),
+      AssignPublicIp: aws.String(ecs.AssignPublicIpEnabled),
+    },
+  }
+}
+
+// checkFargateTaskDef makes sure the task definition can run on Fargate.
+func checkFargateTaskDef(td *ecs.TaskDefinition) (error) {
+  name := taskID(aws.StringValue(td.TaskDefinitionArn))
+  compatible := false
+  for _, c := range td.RequiresCompatibilities {
+    if aws.StringValue(c) == ecs.CompatibilityFargate { compatible = true }
+  }
+  switch {
+  case !compatible:
+    return fmt.Errorf("%s isn't registered for Fargate, try taskdef register --fargate.", name)
+  case aws.StringValue(td.NetworkMode) != ecs.NetworkModeAwsvpc:
+    return fmt.Errorf("%s uses %s networking, Fargate needs awsvpc.", name, aws.StringValue(td.NetworkMode))
+  case td.Cpu == nil || td.Memory == nil:
+    return fmt.Errorf("%s needs task level CPU and memory for Fargate.", name)
+  }
+  return nil
+}
+
+// taskENI is the task's network interface id, private IP and subnet, if it has one.
+func taskENI(task *ecs.Task) (eniID, privateIP, subnetID string) {
+  if task == nil { return eniID, privateIP, subnetID }
+  for _, a := range task.Attachments {
+    if aws.StringValue(a.Type) != eniAttachmentType { continue }
+    for _, d := range a.Details {
+      switch aws.StringValue(d.Name) {
+      case "networkInterfaceId": eniID = aws.StringValue(d.Value)
+      case "privateIPv4Address": privateIP = aws.StringValue(d.Value)
+      case "subnetId": subnetID = aws.StringValue(d.Value)
+      }
+    }
+  }
+  return eniID, privateIP, subnetID
+}
+
+func describeENI(eniID string, sess *session.Session) (*ec2.NetworkInterface, error) {
+  resp, err := ec2.New(sess).DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+    NetworkInterfaceIds: []*string{aws.String(eniID)},
+  })
+  if err != nil { return nil, err }
+  if len(resp.NetworkInterfaces) == 0 { return nil, fmt.Errorf("No network interface %s.", eniID) }
+  return resp.NetworkInterfaces[0], nil
+}
+
+// taskAddresses are the public and private IPs of an awsvpc task's ENI.
+func taskAddresses(task *ecs.Task, sess *session.Session) (public, private string, err error) {
+  eniID, private, _ := taskENI(task)
+  if eniID == "" { return public, private, fmt.Errorf("Task has no network interface.") }
+  eni, err := describeENI(eniID, sess)
+  if err != nil { return public, private, err }
+  if eni.Association != nil { public = aws.StringValue(eni.Association.PublicIp) }
+  if private == "" { private = aws.StringValue(eni.PrivateIpAddress) }
+  return public, private, nil
+}
+
+// getServer is mclib.GetServerFromName with the addresses filled in, use it rather than mclib's.
+func getServer(name, clusterName string, sess *session.Session) (*mclib.Server, error) {
+  s, err := mclib.GetServerFromName(name, clusterName, sess)
+  if err != nil { return s, err }
+  fillServerAddress(s, sess)
+  return s, nil
+}
+
+// getServers is mclib.GetServers with the addresses filled in, use it rather than mclib's.
+func getServers(clusterName string, sess *session.Session) ([]*mclib.Server, error) {
+  servers, err := mclib.GetServers(clusterName, sess)
+  if err != nil { return servers, err }
+  for _, s := range servers { fillServerAddress(s, sess) }
+  return servers, nil
+}
+
+// fillServerAddress fills in the addresses mclib couldn't find, for servers without a container instance.
+func fillServerAddress(s *mclib.Server, sess *session.Session) {
+  if s == nil || s.DeepTask.GetInstanceID() != nil { return }
+  public, private, err := taskAddresses(s.DeepTask.Task, sess)
+  if err != nil { return }
+  if s.PublicServerIp == "" { s.PublicServerIp = public }
+  if s.PrivateServerIp == "" { s.PrivateServerIp = private }
+  if s.ServerPort == "" { s.ServerPort = strconv.Itoa(minecraftContainerPort) }
+  if s.RconPort == "" {
+    s.RconPort = defaultRconPort
+    if env, ok := s.ServerEnvironment(); ok && env[mclib.RconPortKey] != "" { s.RconPort = env[mclib.RconPortKey] }
+  }
+}
+
+// fillProxyAddress gives an awsvpc proxy its ENI's public address.
+func fillProxyAddress(p *mclib.Proxy, clusterName string, sess *session.Session) {
+  if p == nil || p.PublicProxyIp != "" { return }
+  task, err := describeTask(clusterName, p.TaskArn, sess)
+  if err != nil { return }
+  if public, _, err := taskAddresses(task, sess); err == nil { p.PublicProxyIp = public }
+}
+
+// carriedPlacement keeps a Fargate task on Fargate in the same subnet and security groups
+// when it's replaced. EC2 tasks get the default placement.
+func carriedPlacement(task *ecs.Task, sess *session.Session) (pl placement) {
+  if task == nil || aws.StringValue(task.LaunchType) != ecs.LaunchTypeFargate { return pl }
+  pl.LaunchType = launchTypeFargate
+  eniID, _, subnetID := taskENI(task)
+  if subnetID != "" { pl.Subnets = []string{subnetID} }
+  // The ENI goes with the task, so a stopped task may only have its subnet left.
+  eni, err := describeENI(eniID, sess)
+  if err != nil {
+    fmt.Printf("%sCouldn't find the security groups of %s, using the VPC default: %s%s\n", warnColor, eniID, err, resetColor)
+    return pl
+  }
+  if eni.SubnetId != nil { pl.Subnets = []string{*eni.SubnetId} }
+  for _, g := range eni.Groups { pl.SecurityGroups = append(pl.SecurityGroups, aws.StringValue(g.GroupId)) }
+  return pl
+}
+
+// instanceDisplay is the task's instance id, or its launch type if it hasn't got one.
+func instanceDisplay(instanceID *string, task *ecs.Task) string {
+  if instanceID != nil { return *instanceID }
+  if task != nil && task.LaunchType != nil { return strings.ToLower(*task.LaunchType) }
+  return "<none>"
+}
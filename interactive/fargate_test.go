@@ -0,0 +1,82 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestTaskENI(t *testing.T) {
+  task := &ecs.Task{
+    Attachments: []*ecs.Attachment{{
+      Type: aws.String(eniAttachmentType),
+      Details: []*ecs.KeyValuePair{
+        {Name: aws.String("subnetId"), Value: aws.String("subnet-1")},
+        {Name: aws.String("networkInterfaceId"), Value: aws.String("eni-1")},
+        {Name: aws.String("privateIPv4Address"), Value: aws.String("10.0.0.5")},
+      },
+    }},
+    Containers: []*ecs.Container{{Name: aws.String("minecraft")}},
+  }
+  eni, private, subnet := taskENI(task)
+  assert.Equal(t, "eni-1", eni)
+  assert.Equal(t, "10.0.0.5", private)
+  assert.Equal(t, "subnet-1", subnet)
+
+  td := &ecs.TaskDefinition{
+    ContainerDefinitions: []*ecs.ContainerDefinition{{
+      Name: aws.String("minecraft"),
+      PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(25565)}},
+    }},
+  }
+  port, ok := hostPort(task, td, "minecraft", 25565)
+  assert.True(t, ok)
+  assert.Equal(t, int64(25565), port)
+  _, ok = hostPort(task, td, "minecraft", 25577)
+  assert.False(t, ok)
+
+  eni, _, _ = taskENI(&ecs.Task{})
+  assert.Empty(t, eni)
+  _, ok = hostPort(&ecs.Task{Containers: task.Containers}, td, "minecraft", 25565)
+  assert.False(t, ok)
+}
+
+func TestCheckFargateTaskDef(t *testing.T) {
+  td := &ecs.TaskDefinition{
+    TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:1:task-definition/minecraft:3"),
+    RequiresCompatibilities: aws.StringSlice([]string{ecs.CompatibilityEc2, ecs.CompatibilityFargate}),
+    NetworkMode: aws.String(ecs.NetworkModeAwsvpc),
+    Cpu: aws.String("1024"),
+    Memory: aws.String("4096"),
+  }
+  assert.NoError(t, checkFargateTaskDef(td))
+  td.Memory = nil
+  assert.Error(t, checkFargateTaskDef(td))
+  td.Memory = aws.String("4096")
+  td.NetworkMode = aws.String(ecs.NetworkModeBridge)
+  assert.Error(t, checkFargateTaskDef(td))
+  td.RequiresCompatibilities = aws.StringSlice([]string{ecs.CompatibilityEc2})
+  assert.Error(t, checkFargateTaskDef(td))
+}
+
+func TestFargateRunTaskInput(t *testing.T) {
+  pl := placement{LaunchType: launchTypeFargate, Subnets: []string{"subnet-1"}, SecurityGroups: []string{"sg-1"}}
+  assert.NoError(t, pl.validateFargate())
+  in, err := pl.runTaskInput("craft", "td:1", nil, proxyTaskGroup, survivalTaskGroup)
+  if assert.NoError(t, err) {
+    assert.Equal(t, ecs.LaunchTypeFargate, *in.LaunchType)
+    assert.Nil(t, in.PlacementConstraints)
+    assert.Equal(t, "subnet-1", *in.NetworkConfiguration.AwsvpcConfiguration.Subnets[0])
+    assert.Equal(t, ecs.AssignPublicIpEnabled, *in.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp)
+  }
+
+  assert.Error(t, placement{LaunchType: launchTypeFargate}.validateFargate())
+  assert.Error(t, placement{LaunchType: launchTypeFargate, Subnets: []string{"subnet-1"}, AZ: "us-east-1a"}.validateFargate())
+}
+
+func TestInstanceDisplay(t *testing.T) {
+  assert.Equal(t, "i-1", instanceDisplay(aws.String("i-1"), nil))
+  assert.Equal(t, "fargate", instanceDisplay(nil, &ecs.Task{LaunchType: aws.String(ecs.LaunchTypeFargate)}))
+  assert.Equal(t, "<none>", instanceDisplay(nil, nil))
+}
@@ -0,0 +1,297 @@
+package interactive
+
+import (
+  "fmt"
+  "os"
+  "sync"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Fleet upgrades.
+// Restart every server on a cluster onto a new task definition, a batch at a time.
+// Each server saves its world and restarts from a snapshot taken after the save,
+// as env set does. Hibernating servers are left asleep.
+// This runs in the background so that you can pause, resume, abort and check on it.
+// There's one upgrade at a time, whichever cluster it's on.
+//
+
+const (
+  fleetRunning = "running"
+  fleetPaused = "paused"
+  fleetAborted = "aborted"
+  fleetDone = "done"
+
+  defaultFleetParallel = "1"
+  fleetPauseCheck = 2 * time.Second
+)
+
+var (
+  fleetMu sync.Mutex
+  currentUpgrade *fleetUpgrade
+)
+
+func getCurrentUpgrade() (*fleetUpgrade) {
+  fleetMu.Lock()
+  defer fleetMu.Unlock()
+  return currentUpgrade
+}
+
+type upgradeResult struct {
+  User string
+  Server string
+  From string
+  NewTask string
+  Result string
+  Elapsed time.Duration
+  Err error
+}
+
+type fleetUpgrade struct {
+  Cluster string
+  TaskDef string
+  Parallel int
+  ReadyTimeout time.Duration
+  SnapshotTimeout time.Duration
+  Override bool           // Upgrade servers the new task definition takes over quota.
+  Started time.Time
+
+  sess *session.Session
+
+  mu sync.Mutex
+  state string
+  remaining []*mclib.Server
+  results []upgradeResult
+}
+
+func doFleetUpgradeCmd(sess *session.Session) (error) {
+  fleetMu.Lock()
+  defer fleetMu.Unlock()
+  if currentUpgrade != nil && !currentUpgrade.Finished() {
+    return fmt.Errorf("An upgrade of %s is already %s, use \"fleet abort\" first.", currentUpgrade.Cluster, currentUpgrade.State())
+  }
+  if fleetParallelArg < 1 { return fmt.Errorf("--parallel must be at least 1.") }
+
+  td, err := getTaskDefinition(fleetTaskDefArg, sess)
+  if err != nil { return fmt.Errorf("Can't find task definition %s: %s", fleetTaskDefArg, err) }
+
+  servers, err := upgradeCandidates(currentCluster, *td.TaskDefinitionArn, sess)
+  if err != nil { return err }
+  if len(servers) == 0 {
+    fmt.Printf("%sAll servers on %s are already running %s.%s\n", successColor, currentCluster,
+      awslib.ShortArnString(td.TaskDefinitionArn), resetColor)
+    return nil
+  }
+
+  fu := &fleetUpgrade{
+    Cluster: currentCluster,
+    TaskDef: *td.TaskDefinitionArn,
+    Parallel: fleetParallelArg,
+    ReadyTimeout: readyTimeoutArg,
+    SnapshotTimeout: snapshotTimeoutArg,
+    Override: quotaOverrideFlag,
+    sess: sess,
+    state: fleetRunning,
+    remaining: servers,
+    results: make([]upgradeResult, 0),
+    Started: time.Now(),
+  }
+  currentUpgrade = fu
+  fmt.Printf("%sUpgrading %d servers on %s to %s, %d at a time. Use fleet status|pause|resume|abort.%s\n",
+    successColor, len(servers), fu.Cluster, awslib.ShortArnString(td.TaskDefinitionArn), fu.Parallel, resetColor)
+  go fu.run()
+  return nil
+}
+
+func doFleetStatusCmd() (error) {
+  fu := getCurrentUpgrade()
+  if fu == nil {
+    fmt.Printf("%sNo fleet upgrade.%s\n", warnColor, resetColor)
+    return nil
+  }
+  if fu.Cluster != currentCluster {
+    fmt.Printf("%sThe fleet upgrade is on cluster %s, not %s.%s\n", warnColor, fu.Cluster, currentCluster, resetColor)
+  }
+  fu.mu.Lock()
+  fmt.Printf("%sUpgrade of %s to %s %s, started %s (%s). %d done, %d to go.%s\n", titleColor,
+    fu.Cluster, awslib.ShortArnString(&fu.TaskDef), fu.state, fu.Started.Local().Format(time.RFC1123),
+    awslib.ShortDurationString(time.Since(fu.Started)), len(fu.results), len(fu.remaining), resetColor)
+  fu.mu.Unlock()
+  fu.printResults()
+  return nil
+}
+
+func doFleetPauseCmd() (error) { return setUpgradeState(fleetPaused) }
+func doFleetResumeCmd() (error) { return setUpgradeState(fleetRunning) }
+func doFleetAbortCmd() (error) { return setUpgradeState(fleetAborted) }
+
+func setUpgradeState(state string) (error) {
+  fu := getCurrentUpgrade()
+  if fu == nil || fu.Finished() { return fmt.Errorf("No fleet upgrade in progress.") }
+  fu.mu.Lock()
+  fu.state = state
+  fu.mu.Unlock()
+  fmt.Printf("%sFleet upgrade of %s %s. Servers already restarting will finish.%s\n", warnColor, fu.Cluster, state, resetColor)
+  return nil
+}
+
+// upgradeCandidates are the servers, not hubs or sleepers, that aren't already on the task definition.
+func upgradeCandidates(clusterName, tdArn string, sess *session.Session) (servers []*mclib.Server, err error) {
+  all, err := getServers(clusterName, sess)
+  if err != nil { return servers, err }
+  hs, err := loadHibernationStore(hibernatedFile)
+  if err != nil { return servers, err }
+  proxies, _, err := mclib.GetProxies(clusterName, sess)
+  if err != nil { return servers, err }
+  proxyTasks := make(map[string]bool)
+  for _, p := range proxies { proxyTasks[p.TaskArn] = true }
+
+  for _, s := range all {
+    if proxyTasks[*s.TaskArn] { continue }
+    if _, ok := hs.sleeping(s); ok { continue }
+    if *s.DeepTask.TaskDefinition.TaskDefinitionArn == tdArn { continue }
+    servers = append(servers, s)
+  }
+  return servers, err
+}
+
+func (fu *fleetUpgrade) State() string {
+  fu.mu.Lock()
+  defer fu.mu.Unlock()
+  return fu.state
+}
+
+func (fu *fleetUpgrade) Finished() bool {
+  s := fu.State()
+  return s == fleetDone || s == fleetAborted
+}
+
+// nextBatch waits out any pause and returns up to Parallel servers, or nothing if we're finished.
+func (fu *fleetUpgrade) nextBatch() (batch []*mclib.Server) {
+  for {
+    fu.mu.Lock()
+    state := fu.state
+    if state == fleetRunning {
+      n := fu.Parallel
+      if n > len(fu.remaining) { n = len(fu.remaining) }
+      batch = append([]*mclib.Server{}, fu.remaining[:n]...)
+      fu.remaining = fu.remaining[n:]
+      fu.mu.Unlock()
+      return batch
+    }
+    fu.mu.Unlock()
+    if state != fleetPaused { return batch }
+    time.Sleep(fleetPauseCheck)
+  }
+}
+
+func (fu *fleetUpgrade) run() {
+  for batch := fu.nextBatch(); len(batch) > 0; batch = fu.nextBatch() {
+    proxies, _, err := mclib.GetProxies(fu.Cluster, fu.sess)
+    if err != nil {
+      fmt.Printf("\n%sFleet upgrade failed to get proxies, pausing: %s%s\n", failColor, err, resetColor)
+      fu.requeue(batch)
+      fu.pause()
+      continue
+    }
+
+    var wg sync.WaitGroup
+    results := make([]upgradeResult, len(batch))
+    for i, s := range batch {
+      wg.Add(1)
+      go func(i int, s *mclib.Server) {
+        defer wg.Done()
+        results[i] = fu.upgradeServer(s, proxies)
+      }(i, s)
+    }
+    wg.Wait()
+
+    // Health gate: don't go on to the next batch if any of this one didn't come up.
+    failed := 0
+    for _, r := range results {
+      if r.Err != nil { failed++ }
+    }
+    fu.mu.Lock()
+    fu.results = append(fu.results, results...)
+    fu.mu.Unlock()
+    if failed > 0 {
+      fmt.Printf("\n%s%d of %d servers in the last batch failed to upgrade. Fleet upgrade paused, use fleet resume or fleet abort.%s\n",
+        failColor, failed, len(results), resetColor)
+      fu.pause()
+    }
+  }
+
+  fu.mu.Lock()
+  if fu.state != fleetAborted { fu.state = fleetDone }
+  fu.mu.Unlock()
+  fmt.Printf("\n%sFleet upgrade of %s %s after %s.%s\n", titleColor, fu.Cluster, fu.State(),
+    awslib.ShortDurationString(time.Since(fu.Started)), resetColor)
+  fu.printResults()
+}
+
+func (fu *fleetUpgrade) pause() {
+  fu.mu.Lock()
+  if fu.state == fleetRunning { fu.state = fleetPaused }
+  fu.mu.Unlock()
+}
+
+func (fu *fleetUpgrade) requeue(batch []*mclib.Server) {
+  fu.mu.Lock()
+  fu.remaining = append(append([]*mclib.Server{}, batch...), fu.remaining...)
+  fu.mu.Unlock()
+}
+
+func (fu *fleetUpgrade) upgradeServer(s *mclib.Server, proxies []*mclib.Proxy) (r upgradeResult) {
+  start := time.Now()
+  r = upgradeResult{User: s.User, Server: s.Name, From: awslib.ShortArnString(s.DeepTask.TaskDefinition.TaskDefinitionArn)}
+
+  var nServer *mclib.Server
+  snapshot := ""
+  err := checkReplaceQuota(s, fu.TaskDef, fu.Override, fu.sess)
+  if err == nil { snapshot, err = saveAndSnapshot(s, fu.SnapshotTimeout) }
+  if err == nil {
+    nServer, err = restartServer(restartSpec{
+      Server: s,
+      Proxy: serverProxy(s, proxies),
+      Snapshot: snapshot,
+      TaskDef: fu.TaskDef,
+      Cluster: fu.Cluster,
+      ReadyTimeout: fu.ReadyTimeout,
+    }, fu.sess)
+  }
+  r.Elapsed = time.Since(start)
+  r.Err = err
+  if nServer != nil { r.NewTask = awslib.ShortArnString(nServer.TaskArn) }
+  switch {
+  case err == nil: r.Result = "upgraded"
+  case nServer != nil: r.Result = fmt.Sprintf("new task %s, but: %s", r.NewTask, err)
+  default: r.Result = fmt.Sprintf("failed: %s", err)
+  }
+  return r
+}
+
+func (fu *fleetUpgrade) printResults() {
+  fu.mu.Lock()
+  defer fu.mu.Unlock()
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sUser\tServer\tFrom\tNew Task\tElapsed\tResult%s\n", titleColor, resetColor)
+  for _, r := range fu.results {
+    color := successColor
+    if r.Err != nil { color = failColor }
+    fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s%s\n", color, r.User, r.Server, r.From, r.NewTask,
+      awslib.ShortDurationString(r.Elapsed), r.Result, resetColor)
+  }
+  for _, s := range fu.remaining {
+    fmt.Fprintf(w, "%s%s\t%s\t%s\t\t\t%s%s\n", nullColor, s.User, s.Name,
+      awslib.ShortArnString(s.DeepTask.TaskDefinition.TaskDefinitionArn), "waiting", resetColor)
+  }
+  w.Flush()
+}
@@ -0,0 +1,173 @@
+package interactive
+
+import (
+  "fmt"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/route53"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Servers shared by several proxies.
+// The server's FQDN gets a multi-value answer record per proxy, so
+// if one proxy goes the others still answer for it.
+//
+
+// serverProxies returns every proxy that proxies the server.
+func serverProxies(s *mclib.Server, proxies []*mclib.Proxy) (serving []*mclib.Proxy) {
+  for _, p := range proxies {
+    if proxied, err := p.IsServerProxied(s); err == nil && proxied {
+      serving = append(serving, p)
+    }
+  }
+  return serving
+}
+
+// sharedServerFQDN is the FQDN all of the proxies use for the server.
+// They have to agree, or there is nothing to share.
+func sharedServerFQDN(s *mclib.Server, proxies []*mclib.Proxy) (fqdn string, err error) {
+  for _, p := range proxies {
+    dn, err := p.ProxiedServerFQDN(s)
+    if err != nil { return fqdn, err }
+    if fqdn != "" && !sameDNSName(fqdn, dn) {
+      return fqdn, fmt.Errorf("Proxies disagree on the name for %s (%s and %s), it can't be shared.", s.Name, fqdn, dn)
+    }
+    fqdn = dn
+  }
+  if fqdn == "" { return fqdn, fmt.Errorf("No proxies for %s.", s.Name) }
+  return fqdn, err
+}
+
+// shareServer puts the server on another proxy, alongside the ones it's already on.
+func shareServer(s *mclib.Server, p *mclib.Proxy, current []*mclib.Proxy, sess *session.Session) (error) {
+  if err := p.AddServerAccess(s); err != nil { return err }
+  if err := p.StartProxyForServer(s); err != nil { return err }
+  serving := append(append([]*mclib.Proxy{}, current...), p)
+  fqdn, err := sharedServerFQDN(s, serving)
+  if err != nil { return err }
+  return publishServerRecords(fqdn, serving, sess)
+}
+
+// unshareServer takes the server off one proxy, leaving the DNS to the others.
+func unshareServer(s *mclib.Server, p *mclib.Proxy, remaining []*mclib.Proxy, sess *session.Session) (error) {
+  fqdn, err := sharedServerFQDN(s, remaining)
+  if err != nil { return err }
+  if err = publishServerRecords(fqdn, remaining, sess); err != nil { return err }
+  if err = p.StopProxyForServer(s); err != nil { return err }
+  return p.RemoveServerAccess(s)
+}
+
+// swapSharedServer moves each proxy's access and forced host from the old server to the new.
+// DNS points at the proxies, so it doesn't change.
+func swapSharedServer(proxies []*mclib.Proxy, oServer, nServer *mclib.Server) (error) {
+  for _, p := range proxies {
+    if err := p.StopProxyForServer(oServer); err != nil {
+      return fmt.Errorf("Failed to stop %s proxying the old server: %s", p.Name, err)
+    }
+    if err := p.UpdateServerAccess(nServer); err != nil {
+      return fmt.Errorf("Failed to switch %s to the new server: %s", p.Name, err)
+    }
+    if err := p.StartProxyForServer(nServer); err != nil {
+      return fmt.Errorf("Failed to set the forced host on %s for the new server: %s", p.Name, err)
+    }
+    fmt.Printf("%sSwitched %s to the new server.%s\n", successColor, p.Name, resetColor)
+  }
+  return nil
+}
+
+// publishServerRecords replaces whatever A records fqdn has with one
+// multi-value answer record per proxy, in a single change.
+func publishServerRecords(fqdn string, proxies []*mclib.Proxy, sess *session.Session) (error) {
+  svc := route53.New(sess)
+  zones, err := hostedZones(svc)
+  if err != nil { return err }
+  zone := recordZone(fqdn, zones)
+  if zone == nil { return fmt.Errorf("No hosted zone for %s.", fqdn) }
+
+  existing, err := aRecords(svc, *zone.Id, fqdn)
+  if err != nil { return err }
+  var ttl int64 = defaultServerDNSTTL
+  changes := make([]*route53.Change, 0)
+  for _, r := range existing {
+    if r.TTL != nil { ttl = *r.TTL }
+    changes = append(changes, &route53.Change{Action: aws.String(route53.ChangeActionDelete), ResourceRecordSet: r})
+  }
+  for _, p := range proxies {
+    changes = append(changes, &route53.Change{
+      Action: aws.String(route53.ChangeActionCreate),
+      ResourceRecordSet: &route53.ResourceRecordSet{
+        Name: aws.String(fqdn),
+        Type: aws.String(route53.RRTypeA),
+        TTL: aws.Int64(ttl),
+        SetIdentifier: aws.String(p.Name),
+        MultiValueAnswer: aws.Bool(true),
+        ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(p.PublicIpAddress())}},
+      },
+    })
+  }
+  resp, err := svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+    HostedZoneId: zone.Id,
+    ChangeBatch: &route53.ChangeBatch{
+      Comment: aws.String(fmt.Sprintf("%s on %d proxies.", fqdn, len(proxies))),
+      Changes: changes,
+    },
+  })
+  if err != nil { return err }
+  setAlertOnDnsChangeSync(resp.ChangeInfo, sess)
+  return nil
+}
+
+// aRecords are all of the A record sets for name, including multi-value ones.
+func aRecords(svc *route53.Route53, zoneID, name string) (records []*route53.ResourceRecordSet, err error) {
+  params := &route53.ListResourceRecordSetsInput{
+    HostedZoneId: aws.String(zoneID),
+    StartRecordName: aws.String(name),
+    StartRecordType: aws.String(route53.RRTypeA),
+  }
+  err = svc.ListResourceRecordSetsPages(params, func(resp *route53.ListResourceRecordSetsOutput, last bool) bool {
+    for _, r := range resp.ResourceRecordSets {
+      if !sameDNSName(*r.Name, name) || *r.Type != route53.RRTypeA { return false }
+      records = append(records, r)
+    }
+    return true
+  })
+  return records, err
+}
+
+// hasServerRecord is true if fqdn has the proxy's multi-value record.
+func hasServerRecord(fqdn string, p *mclib.Proxy, sess *session.Session) (bool, error) {
+  svc := route53.New(sess)
+  zones, err := hostedZones(svc)
+  if err != nil { return false, err }
+  zone := recordZone(fqdn, zones)
+  if zone == nil { return false, fmt.Errorf("No hosted zone for %s.", fqdn) }
+  records, err := aRecords(svc, *zone.Id, fqdn)
+  if err != nil { return false, err }
+  for _, r := range records {
+    if r.SetIdentifier != nil && *r.SetIdentifier == p.Name { return true, nil }
+  }
+  return false, nil
+}
+
+func proxyNames(proxies []*mclib.Proxy) (names []string) {
+  for _, p := range proxies { names = append(names, p.Name) }
+  return names
+}
+
+// removeServerFromProxy unproxies the server, or if other proxies share it,
+// leaves the DNS to them.
+func removeServerFromProxy(p *mclib.Proxy, s *mclib.Server, proxies []*mclib.Proxy, sess *session.Session) (error) {
+  remaining := withoutProxy(serverProxies(s, proxies), p)
+  if len(remaining) > 0 { return unshareServer(s, p, remaining, sess) }
+  return unproxyServer(p, s, sess)
+}
+
+func withoutProxy(proxies []*mclib.Proxy, p *mclib.Proxy) (others []*mclib.Proxy) {
+  for _, op := range proxies {
+    if op.TaskArn != p.TaskArn { others = append(others, op) }
+  }
+  return others
+}
@@ -0,0 +1,273 @@
+package interactive
+
+import (
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net"
+  "os"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Hibernation.
+// An idle server is saved and restarted onto a sleeper task definition:
+// a lightweight stand-in that answers status pings under the server's name,
+// and exits when someone tries to log in. Proxy access, forced host and DNS
+// follow it through the usual restart, so players connect to the sleeper.
+// When the sleeper exits the server is restarted onto its own task definition
+// from its latest snapshot when it went to sleep. The watchdog waits for a
+// snapshot newer than when the server went idle, so nothing is lost.
+// The sleeper must not archive snapshots and shouldn't map an RCON port.
+//
+
+const (
+  hibernatedFile = "./.ecs-craft_hibernated.json"
+  defaultSleeperTaskDef = "craft-sleeper"
+  defaultIdleAfter = "0s"
+)
+
+type hibernatedServer struct {
+  Cluster string
+  User string
+  Name string
+  TaskDef string          // To wake onto.
+  Snapshot string         // To wake from.
+  SleeperTaskDef string
+  Since time.Time
+}
+
+// Keyed on cluster/user/server, server names are only unique per user.
+type hibernationStore map[string]*hibernatedServer
+
+func hibernationKey(clusterName, userName, serverName string) string {
+  return clusterName + "/" + userName + "/" + serverName
+}
+
+// loadHibernationStore re-keys records from before the key had the user in it.
+func loadHibernationStore(fileName string) (hs hibernationStore, err error) {
+  hs = make(hibernationStore)
+  data, err := ioutil.ReadFile(fileName)
+  if os.IsNotExist(err) { return hs, nil }
+  if err != nil { return hs, err }
+  if err = json.Unmarshal(data, &hs); err != nil { return hs, err }
+  for key, h := range hs {
+    if k := hibernationKey(h.Cluster, h.User, h.Name); k != key {
+      delete(hs, key)
+      hs[k] = h
+    }
+  }
+  return hs, nil
+}
+
+func (hs hibernationStore) save(fileName string) (error) {
+  data, err := json.MarshalIndent(hs, "", "  ")
+  if err != nil { return err }
+  return ioutil.WriteFile(fileName, data, 0600)
+}
+
+// sleeping finds the hibernation record if s is a sleeper.
+func (hs hibernationStore) sleeping(s *mclib.Server) (*hibernatedServer, bool) {
+  h, ok := hs[hibernationKey(s.ClusterName, s.User, s.Name)]
+  if !ok { return nil, false }
+  return h, h.runningAsSleeper(*s.DeepTask.TaskDefinition.TaskDefinitionArn)
+}
+
+// runningAsSleeper says whether a task on tdArn is this record's sleeper, any revision of it.
+func (h *hibernatedServer) runningAsSleeper(tdArn string) bool {
+  return taskDefinitionFamily(tdArn) == taskDefinitionFamily(h.SleeperTaskDef)
+}
+
+// hibernationRecord is the record for s if it's a sleeper, nil otherwise.
+func hibernationRecord(s *mclib.Server) (*hibernatedServer) {
+  hs, err := loadHibernationStore(hibernatedFile)
+  if err != nil {
+    log.Error(fmt.Sprintf("Failed to read %s: %s", hibernatedFile, err))
+    return nil
+  }
+  if h, ok := hs.sleeping(s); ok { return h }
+  return nil
+}
+
+// onlinePlayers asks the server how many players it has.
+func onlinePlayers(s *mclib.Server) (int, error) {
+  status, err := statusPing(net.JoinHostPort(s.PublicServerIp, s.ServerPort), statusProbeTimeout)
+  if err != nil { return 0, err }
+  return status.Players.Online, nil
+}
+
+// hibernateServer saves the world, then puts the sleeper in its place. The latest snapshot
+// must be newer than notBefore, the last time anyone was on.
+func hibernateServer(s *mclib.Server, sleeperTD string, proxies []*mclib.Proxy, readyTimeout time.Duration,
+  notBefore time.Time, sess *session.Session) (sleeper *mclib.Server, err error) {
+  p := serverProxy(s, proxies)
+  if p == nil { return sleeper, fmt.Errorf("%s isn't proxied, there'd be nothing to wake it.", s.Name) }
+  if err = saveWorld(s); err != nil { return sleeper, fmt.Errorf("%s Not hibernating %s.", err, s.Name) }
+  bu, err := s.LatestServerSnapshot()
+  if err != nil { return sleeper, fmt.Errorf("Failed to find the latest snapshot of %s: %s", s.Name, err) }
+  if bu.LastMod().Before(notBefore) {
+    return sleeper, fmt.Errorf("The latest snapshot of %s is from %s, before it was last played. Not hibernating until there's a newer one.",
+      s.Name, bu.LastMod().Local().Format(time.RFC1123))
+  }
+  snapshot := bu.URI()
+  fmt.Printf("%sHibernating %s with snapshot %s taken at %s.%s\n", warnColor, s.Name, snapshot,
+    bu.LastMod().Local().Format(time.RFC1123), resetColor)
+
+  hs, err := loadHibernationStore(hibernatedFile)
+  if err != nil { return sleeper, err }
+  key := hibernationKey(s.ClusterName, s.User, s.Name)
+  hs[key] = &hibernatedServer{
+    Cluster: s.ClusterName,
+    User: s.User,
+    Name: s.Name,
+    TaskDef: *s.DeepTask.TaskDefinition.TaskDefinitionArn,
+    Snapshot: snapshot,
+    SleeperTaskDef: sleeperTD,
+    Since: time.Now(),
+  }
+  if err = hs.save(hibernatedFile); err != nil { return sleeper, err }
+
+  sleeper, err = restartServer(restartSpec{
+    Server: s,
+    Proxy: p,
+    Snapshot: snapshot,
+    TaskDef: sleeperTD,
+    Cluster: s.ClusterName,
+    ReadyTimeout: readyTimeout,
+  }, sess)
+  // The sleeper didn't take over, the server's still awake.
+  if err != nil {
+    delete(hs, key)
+    hs.save(hibernatedFile)
+  }
+  return sleeper, err
+}
+
+// wakeServer restarts a sleeper's server from the snapshot it went to sleep with.
+// The sleeper may have already exited.
+func wakeServer(sleeper *mclib.Server, proxies []*mclib.Proxy, readyTimeout time.Duration,
+  sess *session.Session) (s *mclib.Server, err error) {
+  hs, err := loadHibernationStore(hibernatedFile)
+  if err != nil { return s, err }
+  h, ok := hs.sleeping(sleeper)
+  if !ok { return s, fmt.Errorf("%s isn't hibernating.", sleeper.Name) }
+
+  s, err = restartServer(restartSpec{
+    Server: sleeper,
+    Proxy: serverProxy(sleeper, proxies),
+    Snapshot: h.Snapshot,
+    TaskDef: h.TaskDef,
+    Cluster: sleeper.ClusterName,
+    ReadyTimeout: readyTimeout,
+  }, sess)
+  if err == nil {
+    delete(hs, hibernationKey(sleeper.ClusterName, sleeper.User, sleeper.Name))
+    err = hs.save(hibernatedFile)
+  }
+  return s, err
+}
+
+func doServerHibernateCmd(sess *session.Session) (error) {
+  s, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  if hibernationRecord(s) != nil { return fmt.Errorf("%s is already hibernating.", s.Name) }
+  if err = checkSnapshotQuota(s, quotaOverrideFlag, sess); err != nil { return err }
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+  if _, err = hibernateServer(s, sleeperTaskDefArg, proxies, readyTimeoutArg, time.Time{}, sess); err != nil { return err }
+  fmt.Printf("%s%s is hibernating.%s\n", successColor, s.Name, resetColor)
+  return nil
+}
+
+func doServerWakeCmd(sess *session.Session) (error) {
+  sleeper, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+  s, err := wakeServer(sleeper, proxies, readyTimeoutArg, sess)
+  if err != nil { return err }
+  fmt.Printf("%s%s is awake on %s.%s\n", successColor, s.Name, taskID(*s.TaskArn), resetColor)
+  return nil
+}
+
+//
+// The watchdog's idle policy.
+//
+
+// playerCounts pings the awake servers, leaving out any that don't answer.
+func (wd *watchdog) playerCounts(servers []*mclib.Server) (counts map[string]int) {
+  counts = make(map[string]int)
+  if wd.IdleAfter <= 0 { return counts }
+  for _, s := range servers {
+    if hibernationRecord(s) != nil { continue }
+    online, err := onlinePlayers(s)
+    if err != nil {
+      log.Debug(fmt.Sprintf("Watchdog couldn't ping %s: %s", s.Name, err))
+      continue
+    }
+    counts[*s.TaskArn] = online
+  }
+  return counts
+}
+
+// checkIdle hibernates proxied servers that have had nobody on them for IdleAfter.
+// A failed hibernation is retried with the relaunch backoff, up to MaxAttempts.
+// Called with the lock held.
+func (wd *watchdog) checkIdle(servers map[string]*mclib.Server, counts map[string]int, proxies []*mclib.Proxy) {
+  for arn, s := range servers {
+    online, ok := counts[arn]
+    if !ok || serverProxy(s, proxies) == nil {
+      delete(wd.idleSince, arn)
+      continue
+    }
+    if online > 0 {
+      delete(wd.idleSince, arn)
+      continue
+    }
+    since, ok := wd.idleSince[arn]
+    if !ok {
+      wd.idleSince[arn] = time.Now()
+      continue
+    }
+    if time.Since(since) < wd.IdleAfter { continue }
+    f := wd.hibernateFailures[arn]
+    if f.Count >= wd.MaxAttempts || time.Now().Before(f.RetryAt) { continue }
+    s, arn := s, arn
+    wd.runNow(s.Name, arn, fmt.Sprintf("idle for %s, hibernating", awslib.ShortDurationString(time.Since(since))), func() (error) {
+      _, err := hibernateServer(s, wd.SleeperTaskDef, proxies, wd.ReadyTimeout, since, wd.sess)
+      if err != nil { wd.hibernateFailed(s.Name, arn) }
+      return err
+    })
+  }
+  for arn := range wd.idleSince {
+    if _, ok := servers[arn]; !ok { delete(wd.idleSince, arn) }
+  }
+  for arn := range wd.hibernateFailures {
+    if _, ok := servers[arn]; !ok { delete(wd.hibernateFailures, arn) }
+  }
+}
+
+type hibernateFailure struct {
+  Count int
+  RetryAt time.Time
+}
+
+// hibernateFailed puts off the next try. Takes the lock.
+func (wd *watchdog) hibernateFailed(name, arn string) {
+  wd.mu.Lock()
+  defer wd.mu.Unlock()
+  f := wd.hibernateFailures[arn]
+  f.Count++
+  f.RetryAt = time.Now().Add(wd.backoff(f.Count))
+  wd.hibernateFailures[arn] = f
+  if f.Count >= wd.MaxAttempts {
+    fmt.Printf("%sGiving up on hibernating %s after %d attempts.%s\n", failColor, name, f.Count, resetColor)
+  }
+}
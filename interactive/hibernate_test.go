@@ -0,0 +1,56 @@
+package interactive
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestHibernationStore(t *testing.T) {
+  dir, err := ioutil.TempDir("", "hibernated")
+  assert.NoError(t, err)
+  defer os.RemoveAll(dir)
+  fileName := filepath.Join(dir, "hibernated.json")
+
+  hs, err := loadHibernationStore(fileName)
+  assert.NoError(t, err)
+  assert.Empty(t, hs)
+
+  since := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+  hs[hibernationKey("craft", "jdr", "world")] = &hibernatedServer{
+    Cluster: "craft", User: "jdr", Name: "world",
+    TaskDef: "arn:aws:ecs:us-east-1:123456789012:task-definition/minecraft:7",
+    Snapshot: "jdr/world/snapshots/2017-03-01.zip",
+    SleeperTaskDef: defaultSleeperTaskDef,
+    Since: since,
+  }
+  assert.NoError(t, hs.save(fileName))
+
+  hs, err = loadHibernationStore(fileName)
+  assert.NoError(t, err)
+  h, ok := hs[hibernationKey("craft", "jdr", "world")]
+  if assert.True(t, ok) {
+    assert.Equal(t, "jdr/world/snapshots/2017-03-01.zip", h.Snapshot)
+    assert.True(t, since.Equal(h.Since))
+  }
+
+  // Records keyed without the user are moved to the new key.
+  delete(hs, hibernationKey("craft", "jdr", "world"))
+  hs["craft/world"] = h
+  assert.NoError(t, hs.save(fileName))
+  hs, err = loadHibernationStore(fileName)
+  assert.NoError(t, err)
+  _, ok = hs[hibernationKey("craft", "jdr", "world")]
+  assert.True(t, ok)
+  assert.Len(t, hs, 1)
+}
+
+func TestRunningAsSleeper(t *testing.T) {
+  h := &hibernatedServer{SleeperTaskDef: "craft-sleeper"}
+  assert.True(t, h.runningAsSleeper("arn:aws:ecs:us-east-1:123456789012:task-definition/craft-sleeper:3"))
+  assert.False(t, h.runningAsSleeper("arn:aws:ecs:us-east-1:123456789012:task-definition/minecraft:7"))
+  assert.False(t, h.runningAsSleeper("arn:aws:ecs:us-east-1:123456789012:task-definition/craft-sleeper-big:1"))
+}
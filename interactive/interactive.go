@@ -4,6 +4,7 @@ import (
   "strings"
   "fmt"
   "io"
+  "time"
   "github.com/aws/aws-sdk-go/aws"
   "github.com/aws/aws-sdk-go/aws/session"
   "github.com/aws/aws-sdk-go/service/ecs"
@@ -65,12 +66,26 @@ var (
   proxyListCmd *kingpin.CmdClause
   proxyAttachCmd *kingpin.CmdClause
   proxyDNSCmd *kingpin.CmdClause
-  // proxyRemoveServerCmd *kingpin.CmdClause
+  proxyLogsCmd *kingpin.CmdClause
+  proxyServersCmd *kingpin.CmdClause
+  proxyAccessCmd *kingpin.CmdClause
+  proxyAccessAddCmd *kingpin.CmdClause
+  proxyAccessRmCmd *kingpin.CmdClause
+  proxyForcedHostCmd *kingpin.CmdClause
+  proxyForcedHostSetCmd *kingpin.CmdClause
+  proxyForcedHostRmCmd *kingpin.CmdClause
+  proxyTerminateCmd *kingpin.CmdClause
+  proxyReplaceCmd *kingpin.CmdClause
+
+  proxyMigrateToArg string
+  proxyForceFlag bool
 
   serverCmd *kingpin.CmdClause
   serverLaunchCmd *kingpin.CmdClause
   serverStartCmd *kingpin.CmdClause
   serverRestartCmd *kingpin.CmdClause
+  serverHibernateCmd *kingpin.CmdClause
+  serverWakeCmd *kingpin.CmdClause
   serverTerminateCmd *kingpin.CmdClause
   serverListCmd *kingpin.CmdClause
   serverStatusCmd *kingpin.CmdClause
@@ -78,16 +93,72 @@ var (
   serverAttachCmd *kingpin.CmdClause
   serverProxyCmd *kingpin.CmdClause
   serverUnProxyCmd *kingpin.CmdClause
+  serverMoveProxyCmd *kingpin.CmdClause
+  serverWhyCmd *kingpin.CmdClause
+  serverLogsCmd *kingpin.CmdClause
+  serverOpsCmd *kingpin.CmdClause
+  serverOpsAddCmd *kingpin.CmdClause
+  serverOpsRmCmd *kingpin.CmdClause
+  serverOpsListCmd *kingpin.CmdClause
+  serverWhitelistCmd *kingpin.CmdClause
+  serverWhitelistAddCmd *kingpin.CmdClause
+  serverWhitelistRmCmd *kingpin.CmdClause
+  serverWhitelistListCmd *kingpin.CmdClause
+  serverWhitelistOnCmd *kingpin.CmdClause
+  serverWhitelistOffCmd *kingpin.CmdClause
 
   dnsCmd *kingpin.CmdClause
 
+  watchCmd *kingpin.CmdClause
+  watchServersCmd *kingpin.CmdClause
+  watchStatusCmd *kingpin.CmdClause
+  watchStopCmd *kingpin.CmdClause
+
+  costCmd *kingpin.CmdClause
+  costReportCmd *kingpin.CmdClause
+  costSinceArg string
+  costByArg string
+  costPricesArg string
+  costBucketArg string
+
+  quotaCmd *kingpin.CmdClause
+  quotaShowCmd *kingpin.CmdClause
+  quotaBucketArg string
+  quotaOverrideFlag bool
+
   envCmd *kingpin.CmdClause
   envListCmd *kingpin.CmdClause
+  envDiffCmd *kingpin.CmdClause
+  envSetCmd *kingpin.CmdClause
+
+  envContainerArg string
+  envAllFlag bool
+  envAgainstTaskDefFlag bool
+  otherServerNameArg string
+  envSetArg []string
+  envDryRunFlag bool
+  envNoSaveFlag bool
+  envSnapshotArg string
+  snapshotTimeoutArg time.Duration
 
   clusterArg string
   serverTaskArg string
 
   proxyNameArg string
+  toProxyNameArg string
+  shareFlag bool
+  proxyEIPArg string
+  placementInstanceArg string
+  placementAZArg string
+  placementAttributeArg []string
+  spreadFlag bool
+  binpackFlag bool
+  colocateFlag bool
+  scaleFlag bool
+  scaleTimeoutArg time.Duration
+  launchTypeArg string
+  subnetArg []string
+  securityGroupArg []string
   // Please See getProxyTaskDef() to use this.
   proxyTaskDefArg string
 
@@ -98,9 +169,73 @@ var (
   serverNameArg string
   snapshotNameArg string
   useFullURIFlag bool
+  readyTimeoutArg time.Duration
+  replaceFlag bool
+  allowDuplicateFlag bool
+  keepFailedFlag bool
+  serverSetArg []string
+  serverPropsArg string
+  playerNameArg string
+  playerLookupArg string
+  playerUserArg string
+
+  watchIntervalArg time.Duration
+  watchBackoffArg time.Duration
+  watchMaxAttemptsArg int
+  watchSpotQueueArg string
+  watchIdleAfterArg time.Duration
+  sleeperTaskDefArg string
+
+  whyLinesArg int64
+  whyCountArg int
+
+  logsContainerArg string
+  logsFollowFlag bool
+  logsSinceArg time.Duration
+  logsEndpointArg string
+
+  metricsWindowArg time.Duration
 
   archiveCmd *kingpin.CmdClause
   archiveListCmd *kingpin.CmdClause
+
+  fleetCmd *kingpin.CmdClause
+  fleetUpgradeCmd *kingpin.CmdClause
+  fleetStatusCmd *kingpin.CmdClause
+  fleetPauseCmd *kingpin.CmdClause
+  fleetResumeCmd *kingpin.CmdClause
+  fleetAbortCmd *kingpin.CmdClause
+
+  fleetTaskDefArg string
+  fleetParallelArg int
+
+  taskDefCmd *kingpin.CmdClause
+  taskDefListCmd *kingpin.CmdClause
+  taskDefShowCmd *kingpin.CmdClause
+  taskDefRegisterCmd *kingpin.CmdClause
+  taskDefDiffCmd *kingpin.CmdClause
+
+  taskDefArg string
+  taskDefOtherArg string
+  taskDefRevealFlag bool
+  taskDefFamilyArg string
+  taskDefFromArg string
+  taskDefTemplateArg string
+  taskDefContainerArg string
+  taskDefImageTagArg string
+  taskDefMemoryArg int64
+  taskDefMemoryReservationArg int64
+  taskDefJVMOptsArg string
+  taskDefVolumesArg []string
+  taskDefEFSVolumesArg []string
+  taskDefFargateFlag bool
+  taskDefTaskCPUArg string
+  taskDefTaskMemoryArg string
+  taskDefExecutionRoleArg string
+  taskDefUlimitsArg []string
+  taskDefLogGroupArg string
+  taskDefLogPrefixArg string
+  taskDefLogRegionArg string
 )
 
 // Text Coloring
@@ -154,8 +289,22 @@ func init() {
   // Env Commands
   envCmd = app.Command("env", "Context for environment commands")
   envListCmd = envCmd.Command("list", "Print out an environment.")
+  envListCmd.Flag("container", "Container to list, defaults to the minecraft or bungee container.").Default("").StringVar(&envContainerArg)
+  envListCmd.Flag("all", "List every container's environment.").Default("false").BoolVar(&envAllFlag)
   envListCmd.Arg("server-name", "List this proxy or server environment.").Required().StringVar(&serverNameArg)
   envListCmd.Arg("cluster", "The cluster where you'll find server.").Action(setCurrent).StringVar(&clusterArg)
+  envDiffCmd = envCmd.Command("diff", "Show the environment differences between two servers, or a server and its task definition.")
+  envDiffCmd.Flag("against-taskdef", "Compare the server with its own task definition.").Default("false").BoolVar(&envAgainstTaskDefFlag)
+  envDiffCmd.Arg("server-name", "Proxy or server to compare from.").Required().StringVar(&serverNameArg)
+  envDiffCmd.Arg("other-server-name", "Proxy or server to compare to.").Default("").StringVar(&otherServerNameArg)
+  envSetCmd = envCmd.Command("set", "Change settings on a running server: snapshot it and restart it onto a new task with the new environment.")
+  envSetCmd.Flag("dry-run", "Only show what would change.").Default("false").BoolVar(&envDryRunFlag)
+  envSetCmd.Flag("no-save", "Restart from the latest snapshot without saving, losing anything since.").Default("false").BoolVar(&envNoSaveFlag)
+  envSetCmd.Flag("snapshot", "Restart from this snapshot, without saving.").Default("").StringVar(&envSnapshotArg)
+  envSetCmd.Flag("snapshot-timeout", "How long to wait for a snapshot after saving the world.").Default(defaultSnapshotTimeout).DurationVar(&snapshotTimeoutArg)
+  envSetCmd.Flag("ready-timeout", "How long to wait for the new server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  envSetCmd.Arg("server-name", "The server to change.").Required().StringVar(&serverNameArg)
+  envSetCmd.Arg("settings", "KEY=VALUE settings, by environment key or server.properties name.").Required().StringsVar(&envSetArg)
 
   // Proxy commands
   proxyCmd = app.Command("proxy", "Context for the proxy commands.")
@@ -164,12 +313,27 @@ func init() {
   proxyListCmd.Arg("cluster", "The cluster where you'll find proxy tasks.").Action(setCurrent).StringVar(&clusterArg)
 
   proxyLaunchCmd = proxyCmd.Command("launch", "Launch a proxy into the cluster")
+  proxyLaunchCmd.Flag("ready-timeout", "How long to wait for the proxy to accept connections before giving up on attaching it to the network.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  proxyLaunchCmd.Flag("eip", "Elastic IP allocation id, or auto to reuse or allocate one tagged for the proxy.").Default("").StringVar(&proxyEIPArg)
+  proxyLaunchCmd.Flag("instance", "Place the proxy on this EC2 instance.").Default("").StringVar(&placementInstanceArg)
+  proxyLaunchCmd.Flag("az", "Place the proxy in this availability zone.").Default("").StringVar(&placementAZArg)
+  proxyLaunchCmd.Flag("attribute", "Place the proxy on an instance with this attribute, as name=value, can repeat.").StringsVar(&placementAttributeArg)
+  proxyLaunchCmd.Flag("spread", "Spread across availability zones and instances.").Default("false").BoolVar(&spreadFlag)
+  proxyLaunchCmd.Flag("binpack", "Pack onto the instance with the least memory left.").Default("false").BoolVar(&binpackFlag)
+  proxyLaunchCmd.Flag("colocate", "Allow proxies and survival servers on the same instance.").Default("false").BoolVar(&colocateFlag)
+  proxyLaunchCmd.Flag("scale", "If no instance has room, grow the cluster's auto scaling group by one and wait for the new instance.").Default("false").BoolVar(&scaleFlag)
+  proxyLaunchCmd.Flag("scale-timeout", "How long to wait for a new instance with --scale.").Default(defaultScaleTimeout).DurationVar(&scaleTimeoutArg)
+  proxyLaunchCmd.Flag("launch-type", "Run the proxy on the cluster's EC2 instances or on Fargate.").Default("").EnumVar(&launchTypeArg, "", launchTypeEC2, launchTypeFargate)
+  proxyLaunchCmd.Flag("subnet", "Subnet for a Fargate proxy, can repeat.").StringsVar(&subnetArg)
+  proxyLaunchCmd.Flag("security-group", "Security group for a Fargate proxy, can repeat.").StringsVar(&securityGroupArg)
   proxyLaunchCmd.Arg("proxy-name", "Name for the launched proxy.").Required().StringVar(&proxyNameArg)
   proxyLaunchCmd.Arg("cluster", "ECS Cluster for the lauched proxy.").Action(setCurrent).StringVar(&clusterArg)
   proxyLaunchCmd.Arg("ecs-task","ECS Task definig containers etc, to used in launching the proxy. You can choose \"defaultCraftPort\", \"defaultRandomPort\", or any valid task-definition").Default(defaultProxyTaskDef).StringVar(&proxyTaskDefArg)
   // proxyLaunchCmd.Flag("port", "Choose either the default craft port (25565) or a random port selected at container launch.").Default(proxyUnselectedPort).EnumVar(&proxyPortArg, proxyUnselectedPort, proxyDefaultPort, proxyRandomPort)
 
   proxyAttachCmd = proxyCmd.Command("attach", "Attach proxy to the network by hand.")
+  proxyAttachCmd.Flag("ready-timeout", "How long to wait for the proxy to accept connections.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  proxyAttachCmd.Flag("eip", "Elastic IP allocation id, or auto to reuse or allocate one tagged for the proxy.").Default("").StringVar(&proxyEIPArg)
   proxyAttachCmd.Arg("proxy-name", "Name of the proxy you want to attach to the network.").Required().StringVar(&proxyNameArg)
   proxyAttachCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
 
@@ -177,16 +341,72 @@ func init() {
   proxyDNSCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
   proxyDNSCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
 
-  // proxyRemoveServerCmd = proxyCmd.Command("remove", "Removes the server-name from the proxies list of servers. DOES NOT manipulate dns or forced hosts. Normally use unproxy.")
-  // proxyRemoveServerCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
-  // proxyRemoveServerCmd.Arg("server-name", "Name to use in server remove.").Required().StringVar(&serverNameArg)
-  // proxyRemoveServerCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+  proxyLogsCmd = proxyCmd.Command("logs", "Show the logs for a proxy's containers.")
+  proxyLogsCmd.Flag("container", "Container to show logs for, defaults to the proxy.").Default("").StringVar(&logsContainerArg)
+  proxyLogsCmd.Flag("follow", "Keep printing new log lines as they arrive.").Short('f').Default("false").BoolVar(&logsFollowFlag)
+  proxyLogsCmd.Flag("since", "Show logs from this long ago.").Default(defaultLogsSince).DurationVar(&logsSinceArg)
+  proxyLogsCmd.Flag("endpoint", "CloudWatch Logs endpoint to use instead of AWS's.").Default("").StringVar(&logsEndpointArg)
+  proxyLogsCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyLogsCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+
+  proxyServersCmd = proxyCmd.Command("servers", "Show the servers a proxy can reach and its forced hosts.")
+  proxyServersCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyServersCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+
+  proxyAccessCmd = proxyCmd.Command("access", "Context for the proxy's list of servers. DOES NOT manipulate dns or forced hosts. Normally use server proxy/unproxy.")
+  proxyAccessAddCmd = proxyAccessCmd.Command("add", "Add a server to the proxy's list of servers.")
+  proxyAccessAddCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyAccessAddCmd.Arg("server-name", "Name of the server.").Required().StringVar(&serverNameArg)
+  proxyAccessAddCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+  proxyAccessRmCmd = proxyAccessCmd.Command("rm", "Remove a server from the proxy's list of servers.")
+  proxyAccessRmCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyAccessRmCmd.Arg("server-name", "Name of the server.").Required().StringVar(&serverNameArg)
+  proxyAccessRmCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+
+  proxyForcedHostCmd = proxyCmd.Command("forced-host", "Context for the proxy's forced hosts. DOES NOT manipulate dns or access.")
+  proxyForcedHostSetCmd = proxyForcedHostCmd.Command("set", "Forward the server's FQDN to the server.")
+  proxyForcedHostSetCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyForcedHostSetCmd.Arg("server-name", "Name of the server.").Required().StringVar(&serverNameArg)
+  proxyForcedHostSetCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+  proxyForcedHostRmCmd = proxyForcedHostCmd.Command("rm", "Stop forwarding the server's FQDN.")
+  proxyForcedHostRmCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyForcedHostRmCmd.Arg("server-name", "Name of the server.").Required().StringVar(&serverNameArg)
+  proxyForcedHostRmCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+
+  proxyTerminateCmd = proxyCmd.Command("terminate", "Take every server off the proxy, remove its DNS and stop it.")
+  proxyTerminateCmd.Flag("to", "Move the servers to this proxy instead of unproxying them.").Default("").StringVar(&proxyMigrateToArg)
+  proxyTerminateCmd.Flag("force", "Terminate even if some servers couldn't be moved off.").Default("false").BoolVar(&proxyForceFlag)
+  proxyTerminateCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyTerminateCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
+
+  proxyReplaceCmd = proxyCmd.Command("replace", "Launch a new proxy with the same name and servers, move DNS to it and stop the old one.")
+  proxyReplaceCmd.Flag("taskdef", "Task definition for the new proxy, defaults to the old proxy's.").Default("").StringVar(&proxyTaskDefArg)
+  proxyReplaceCmd.Flag("ready-timeout", "How long to wait for the new proxy to accept connections.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  proxyReplaceCmd.Arg("proxy-name", "Name of the proxy.").Required().StringVar(&proxyNameArg)
+  proxyReplaceCmd.Arg("cluster", "The cluster where you'll find the proxy.").Action(setCurrent).StringVar(&clusterArg)
 
 
   // Server commands
   serverCmd = app.Command("server","Context for minecraft server commands.")
 
   serverLaunchCmd = serverCmd.Command("launch", "Launch a new minecraft server for a user in a cluster.")
+  serverLaunchCmd.Flag("ready-timeout", "How long to wait for the server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  serverLaunchCmd.Flag("replace", "If the server is already running, restart it from its latest snapshot instead.").Default("false").BoolVar(&replaceFlag)
+  serverLaunchCmd.Flag("override", "Launch even if it takes the user over quota.").Default("false").BoolVar(&quotaOverrideFlag)
+  serverLaunchCmd.Flag("allow-duplicate", "Launch even if a server with the same user and name is already running.").Default("false").BoolVar(&allowDuplicateFlag)
+  serverLaunchCmd.Flag("set", "Server setting as KEY=VALUE, by environment key or server.properties name, can repeat.").StringsVar(&serverSetArg)
+  serverLaunchCmd.Flag("props", "A server.properties file to take settings from.").Default("").StringVar(&serverPropsArg)
+  serverLaunchCmd.Flag("instance", "Place the server on this EC2 instance.").Default("").StringVar(&placementInstanceArg)
+  serverLaunchCmd.Flag("az", "Place the server in this availability zone.").Default("").StringVar(&placementAZArg)
+  serverLaunchCmd.Flag("attribute", "Place the server on an instance with this attribute, as name=value, can repeat.").StringsVar(&placementAttributeArg)
+  serverLaunchCmd.Flag("spread", "Spread across availability zones and instances.").Default("false").BoolVar(&spreadFlag)
+  serverLaunchCmd.Flag("binpack", "Pack onto the instance with the least memory left.").Default("false").BoolVar(&binpackFlag)
+  serverLaunchCmd.Flag("colocate", "Allow proxies and survival servers on the same instance.").Default("false").BoolVar(&colocateFlag)
+  serverLaunchCmd.Flag("scale", "If no instance has room, grow the cluster's auto scaling group by one and wait for the new instance.").Default("false").BoolVar(&scaleFlag)
+  serverLaunchCmd.Flag("scale-timeout", "How long to wait for a new instance with --scale.").Default(defaultScaleTimeout).DurationVar(&scaleTimeoutArg)
+  serverLaunchCmd.Flag("launch-type", "Run the server on the cluster's EC2 instances or on Fargate.").Default("").EnumVar(&launchTypeArg, "", launchTypeEC2, launchTypeFargate)
+  serverLaunchCmd.Flag("subnet", "Subnet for a Fargate server, can repeat.").StringsVar(&subnetArg)
+  serverLaunchCmd.Flag("security-group", "Security group for a Fargate server, can repeat.").StringsVar(&securityGroupArg)
   serverLaunchCmd.Arg("user", "User name of the server").Required().StringVar(&userNameArg)
   serverLaunchCmd.Arg("server-name","Name of the server. This is an identifier for the serve. (e.g. test-server, world-play).").Required().StringVar(&serverNameArg)
   serverLaunchCmd.Arg("cluster", "ECS cluster to launch the server in.").Action(setCurrent).StringVar(&clusterArg)
@@ -195,6 +415,23 @@ func init() {
 
   serverStartCmd = serverCmd.Command("start", "Start a server from a snapshot.")
   serverStartCmd.Flag("useFullURI", "Use a full URI for the snapshot as opposed to a named snapshot.").Default("false").BoolVar(&useFullURIFlag)
+  serverStartCmd.Flag("ready-timeout", "How long to wait for the server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  serverStartCmd.Flag("replace", "If the server is already running, restart it from the snapshot instead.").Default("false").BoolVar(&replaceFlag)
+  serverStartCmd.Flag("override", "Start even if it takes the user over quota.").Default("false").BoolVar(&quotaOverrideFlag)
+  serverStartCmd.Flag("allow-duplicate", "Start even if a server with the same user and name is already running.").Default("false").BoolVar(&allowDuplicateFlag)
+  serverStartCmd.Flag("set", "Server setting as KEY=VALUE, by environment key or server.properties name, can repeat.").StringsVar(&serverSetArg)
+  serverStartCmd.Flag("props", "A server.properties file to take settings from.").Default("").StringVar(&serverPropsArg)
+  serverStartCmd.Flag("instance", "Place the server on this EC2 instance.").Default("").StringVar(&placementInstanceArg)
+  serverStartCmd.Flag("az", "Place the server in this availability zone.").Default("").StringVar(&placementAZArg)
+  serverStartCmd.Flag("attribute", "Place the server on an instance with this attribute, as name=value, can repeat.").StringsVar(&placementAttributeArg)
+  serverStartCmd.Flag("spread", "Spread across availability zones and instances.").Default("false").BoolVar(&spreadFlag)
+  serverStartCmd.Flag("binpack", "Pack onto the instance with the least memory left.").Default("false").BoolVar(&binpackFlag)
+  serverStartCmd.Flag("colocate", "Allow proxies and survival servers on the same instance.").Default("false").BoolVar(&colocateFlag)
+  serverStartCmd.Flag("scale", "If no instance has room, grow the cluster's auto scaling group by one and wait for the new instance.").Default("false").BoolVar(&scaleFlag)
+  serverStartCmd.Flag("scale-timeout", "How long to wait for a new instance with --scale.").Default(defaultScaleTimeout).DurationVar(&scaleTimeoutArg)
+  serverStartCmd.Flag("launch-type", "Run the server on the cluster's EC2 instances or on Fargate.").Default("").EnumVar(&launchTypeArg, "", launchTypeEC2, launchTypeFargate)
+  serverStartCmd.Flag("subnet", "Subnet for a Fargate server, can repeat.").StringsVar(&subnetArg)
+  serverStartCmd.Flag("security-group", "Security group for a Fargate server, can repeat.").StringsVar(&securityGroupArg)
   serverStartCmd.Arg("user","User name for the server.").Required().StringVar(&userNameArg)
   serverStartCmd.Arg("server-name","Name of the server. This is an identifier for the serve. (e.g. test-server, world-play).").Required().StringVar(&serverNameArg)
   serverStartCmd.Arg("snapshot", "Name of snapshot for starting server.").Required().StringVar(&snapshotNameArg)
@@ -203,12 +440,27 @@ func init() {
   // serverStartCmd.Arg("ecs-conatiner-name", "Container name for the minecraft server (used for environment variables.").Default("minecraft").StringVar(&serverContainerNameArg)
 
   serverRestartCmd = serverCmd.Command("restart", "Restart a server, using the latest backup.")
+  serverRestartCmd.Flag("override", "Restart onto the task definition even if it takes the user over quota.").Default("false").BoolVar(&quotaOverrideFlag)
+  serverRestartCmd.Flag("keep-failed", "Leave the new server running if it doesn't become ready, to look at.").Default("false").BoolVar(&keepFailedFlag)
+  serverRestartCmd.Flag("ready-timeout", "How long to wait for the new server to accept logins before switching over to it.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
   serverRestartCmd.Arg("server-name","Name of the server. This is an identifier for the serve. (e.g. test-server, world-play).").Required().StringVar(&serverNameArg)
   serverRestartCmd.Arg("proxy", "The name of the proxy.").Required().StringVar(&proxyNameArg)
   serverRestartCmd.Arg("snapshot", "Name of snapshot for starting server.").Default("").StringVar(&snapshotNameArg)
   serverRestartCmd.Arg("cluster", "ECS Cluster for the server containers.").Action(setCurrent).StringVar(&clusterArg)
   serverRestartCmd.Arg("ecs-task", "ECS Task that represents a running minecraft server.").Default(defaultServerTaskDef).StringVar(&serverTaskArg)
 
+  serverHibernateCmd = serverCmd.Command("hibernate", "Snapshot a server and put a sleeper in its place, which wakes the server when someone tries to log in.")
+  serverHibernateCmd.Flag("override", "Snapshot even if the user is over their archive quota.").Default("false").BoolVar(&quotaOverrideFlag)
+  serverHibernateCmd.Flag("sleeper-taskdef", "Task definition of the sleeper.").Default(defaultSleeperTaskDef).StringVar(&sleeperTaskDefArg)
+  serverHibernateCmd.Flag("ready-timeout", "How long to wait for the sleeper to accept connections.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  serverHibernateCmd.Arg("server", "The server to hibernate.").Required().StringVar(&serverNameArg)
+  serverHibernateCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
+  serverWakeCmd = serverCmd.Command("wake", "Restart a hibernating server from the snapshot it went to sleep with.")
+  serverWakeCmd.Flag("ready-timeout", "How long to wait for the server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  serverWakeCmd.Arg("server", "The server to wake.").Required().StringVar(&serverNameArg)
+  serverWakeCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
   serverTerminateCmd = serverCmd.Command("terminate", "Stop this server")
   serverTerminateCmd.Arg("server-name", "ECS Task ARN for this server.").Required().StringVar(&serverNameArg)
   serverTerminateCmd.Arg("cluster", "ECS cluster to look for server.").Action(setCurrent).StringVar(&clusterArg)
@@ -220,10 +472,13 @@ func init() {
   serverStatusCmd.Arg("cluster", "ECS cstatuser to look for servers.").Action(setCurrent).StringVar(&clusterArg)
 
   serverDescribeCmd = serverCmd.Command("describe", "Show some details for a users server.")
+  serverDescribeCmd.Flag("window", "Period to summarize metrics over.").Default(defaultMetricsWindow).DurationVar(&metricsWindowArg)
   serverDescribeCmd.Arg("server", "The server to describe.").Required().StringVar(&serverNameArg)
   serverDescribeCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
 
   serverProxyCmd = serverCmd.Command("proxy", "This puts a server under a proxy. Making it avaible to proxy members, and using the proxy as a DNS proxy for the server.")
+  serverProxyCmd.Flag("ready-timeout", "How long to wait for the server to accept logins before proxying it.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  serverProxyCmd.Flag("share", "If the server is already proxied, add this proxy as well and publish DNS for all of them.").Default("false").BoolVar(&shareFlag)
   serverProxyCmd.Arg("server", "Name of server to attach to proxy.").Required().StringVar(&serverNameArg)
   serverProxyCmd.Arg("proxy", "The name of the proxy.").Required().StringVar(&proxyNameArg)
   serverProxyCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
@@ -233,15 +488,153 @@ func init() {
   serverUnProxyCmd.Arg("proxy", "Name of the proxy with server to remove.").Required().StringVar(&proxyNameArg)
   serverUnProxyCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
 
+  serverMoveProxyCmd = serverCmd.Command("move-proxy", "Move a server from one proxy to another, keeping its DNS record throughout.")
+  serverMoveProxyCmd.Arg("server", "Name of the server to move.").Required().StringVar(&serverNameArg)
+  serverMoveProxyCmd.Arg("from-proxy", "Proxy the server is on now.").Required().StringVar(&proxyNameArg)
+  serverMoveProxyCmd.Arg("to-proxy", "Proxy to move it to.").Required().StringVar(&toProxyNameArg)
+  serverMoveProxyCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
+  serverWhyCmd = serverCmd.Command("why", "Describe recently stopped tasks for a server: why they stopped, exit codes and the end of the logs.")
+  serverWhyCmd.Flag("lines", "Number of log lines to show for each container.").Default(defaultWhyLines).Int64Var(&whyLinesArg)
+  serverWhyCmd.Flag("count", "Only show this many of the most recently stopped tasks (0 for all).").Default("1").IntVar(&whyCountArg)
+  serverWhyCmd.Arg("server", "Name of the server, or a task arn.").Required().StringVar(&serverNameArg)
+  serverWhyCmd.Arg("cluster", "The ECS cluster where the server lived.").Action(setCurrent).StringVar(&clusterArg)
+
+  serverLogsCmd = serverCmd.Command("logs", "Show the logs for a server's containers.")
+  serverLogsCmd.Flag("container", "Container to show logs for: minecraft or controller.").Default(serverContainerName).EnumVar(&logsContainerArg, serverContainerName, controllerContainerName)
+  serverLogsCmd.Flag("follow", "Keep printing new log lines as they arrive.").Short('f').Default("false").BoolVar(&logsFollowFlag)
+  serverLogsCmd.Flag("since", "Show logs from this long ago.").Default(defaultLogsSince).DurationVar(&logsSinceArg)
+  serverLogsCmd.Flag("endpoint", "CloudWatch Logs endpoint to use instead of AWS's.").Default("").StringVar(&logsEndpointArg)
+  serverLogsCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverLogsCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
+  serverOpsCmd = serverCmd.Command("ops", "Context for a server's operators, kept across restarts.")
+  serverOpsAddCmd = serverOpsCmd.Command("add", "Make a player an operator.")
+  serverOpsAddCmd.Flag("lookup", "How to find the player's UUID: mojang or offline.").Default(mojangLookupName).EnumVar(&playerLookupArg, mojangLookupName, offlineLookupName)
+  serverOpsAddCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverOpsAddCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverOpsAddCmd.Arg("player", "Player name.").Required().StringVar(&playerNameArg)
+  serverOpsAddCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverOpsRmCmd = serverOpsCmd.Command("rm", "Take operator away from a player.")
+  serverOpsRmCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverOpsRmCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverOpsRmCmd.Arg("player", "Player name or UUID.").Required().StringVar(&playerNameArg)
+  serverOpsRmCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverOpsListCmd = serverOpsCmd.Command("list", "List a server's operators.")
+  serverOpsListCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverOpsListCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverOpsListCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
+  serverWhitelistCmd = serverCmd.Command("whitelist", "Context for a server's whitelist, kept across restarts.")
+  serverWhitelistAddCmd = serverWhitelistCmd.Command("add", "Add a player to the whitelist.")
+  serverWhitelistAddCmd.Flag("lookup", "How to find the player's UUID: mojang or offline.").Default(mojangLookupName).EnumVar(&playerLookupArg, mojangLookupName, offlineLookupName)
+  serverWhitelistAddCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverWhitelistAddCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverWhitelistAddCmd.Arg("player", "Player name.").Required().StringVar(&playerNameArg)
+  serverWhitelistAddCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverWhitelistRmCmd = serverWhitelistCmd.Command("rm", "Remove a player from the whitelist.")
+  serverWhitelistRmCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverWhitelistRmCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverWhitelistRmCmd.Arg("player", "Player name or UUID.").Required().StringVar(&playerNameArg)
+  serverWhitelistRmCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverWhitelistListCmd = serverWhitelistCmd.Command("list", "List the whitelist.")
+  serverWhitelistListCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverWhitelistListCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverWhitelistListCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverWhitelistOnCmd = serverWhitelistCmd.Command("on", "Only let whitelisted players in.")
+  serverWhitelistOnCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverWhitelistOnCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverWhitelistOnCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+  serverWhitelistOffCmd = serverWhitelistCmd.Command("off", "Let anyone in.")
+  serverWhitelistOffCmd.Flag("user", "The server's user, needed if it isn't running and more than one user has a server by that name.").Default("").StringVar(&playerUserArg)
+  serverWhitelistOffCmd.Arg("server", "Name of the server.").Required().StringVar(&serverNameArg)
+  serverWhitelistOffCmd.Arg("cluster", "The ECS cluster where the server lives.").Action(setCurrent).StringVar(&clusterArg)
+
   // DNS 
   dnsCmd = app.Command("dns", "List Craft DNS for the network.")  
 
+  // Watchdog
+  watchCmd = app.Command("watch", "Context for watching the cluster in the background.")
+  watchServersCmd = watchCmd.Command("servers", "Watch for server and proxy tasks that stop without being terminated and relaunch them.")
+  watchServersCmd.Flag("interval", "How often to check on the cluster.").Default(defaultWatchInterval).DurationVar(&watchIntervalArg)
+  watchServersCmd.Flag("backoff", "Wait this long before the first relaunch, doubling for each attempt after.").Default(defaultWatchBackoff).DurationVar(&watchBackoffArg)
+  watchServersCmd.Flag("max-attempts", "Give up relaunching after this many attempts.").Default(defaultWatchMaxAttempts).IntVar(&watchMaxAttemptsArg)
+  watchServersCmd.Flag("ready-timeout", "How long to wait for a relaunched server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  watchServersCmd.Flag("spot-queue", "SQS queue URL getting EC2 spot interruption warnings, servers and proxies on interrupted instances are moved.").Default("").StringVar(&watchSpotQueueArg)
+  watchServersCmd.Flag("idle-after", "Hibernate proxied servers that have had nobody on them this long, 0 never.").Default(defaultIdleAfter).DurationVar(&watchIdleAfterArg)
+  watchServersCmd.Flag("sleeper-taskdef", "Task definition of the sleeper hibernated servers are replaced with.").Default(defaultSleeperTaskDef).StringVar(&sleeperTaskDefArg)
+  watchServersCmd.Arg("cluster", "The cluster to watch.").Action(setCurrent).StringVar(&clusterArg)
+  watchStatusCmd = watchCmd.Command("status", "What the watchdog is watching and what it has done.")
+  watchStopCmd = watchCmd.Command("stop", "Stop watching.")
+
+  // Costs
+  costCmd = app.Command("cost", "Context for cost commands.")
+  costReportCmd = costCmd.Command("report", "What running and recently stopped tasks and archive storage have cost, across all clusters.")
+  costReportCmd.Flag("since", "How far back to report, e.g. 30d or 12h.").Default(defaultCostSince).StringVar(&costSinceArg)
+  costReportCmd.Flag("by", "Report by user, server or cluster.").Default(costByUser).EnumVar(&costByArg, costByUser, costByServer, costByCluster)
+  costReportCmd.Flag("prices", "JSON price table: Instances (hourly by instance type), FargateVCPUHour, FargateGBHour, S3GBMonth.").Default(pricesFile).StringVar(&costPricesArg)
+  costReportCmd.Flag("bucket", "The S3 bucket snapshots are archived in.").Default(DefaultArchiveBucket).StringVar(&costBucketArg)
+
+  // Quotas
+  quotaCmd = app.Command("quota", "Context for per-user quota commands, quotas are set in " + quotaFile + ".")
+  quotaShowCmd = quotaCmd.Command("show", "Show a user's quotas and what they're using against them.")
+  quotaShowCmd.Flag("bucket", "The S3 bucket snapshots are archived in.").Default(DefaultArchiveBucket).StringVar(&quotaBucketArg)
+  quotaShowCmd.Arg("user", "The user.").Required().StringVar(&userNameArg)
+
   // Snapshot commands
   archiveCmd = app.Command("archive", "Context for snapshot commands.")
   archiveListCmd = archiveCmd.Command("list", "List all snapshot for a user.")
   archiveListCmd.Arg("user", "The snapshot's user.").Required().StringVar(&userNameArg)
   archiveListCmd.Arg("bucket", "The name of the S3 bucket we're using to store snapshots in.").Default("craft-config-test").StringVar(&bucketNameArg)
 
+  // Task definitions
+  taskDefCmd = app.Command("taskdef", "Context for task definition commands.")
+  taskDefListCmd = taskDefCmd.Command("list", "List active task definitions.")
+  taskDefListCmd.Arg("family-prefix", "Only list families starting with this.").Default("").StringVar(&taskDefFamilyArg)
+
+  taskDefShowCmd = taskDefCmd.Command("show", "Show a task definition.")
+  taskDefShowCmd.Flag("reveal", "Show secret looking environment values.").Default("false").BoolVar(&taskDefRevealFlag)
+  taskDefShowCmd.Arg("task-definition", "Family, family:revision or arn.").Required().StringVar(&taskDefArg)
+
+  taskDefRegisterCmd = taskDefCmd.Command("register", "Register a new task definition revision from an existing one or a template.")
+  taskDefRegisterCmd.Flag("from", "Task definition to start from.").Default("").StringVar(&taskDefFromArg)
+  taskDefRegisterCmd.Flag("template", "JSON template file (register-task-definition input) to render, it can use the flag values, eg. {{.ImageTag}}.").Default("").StringVar(&taskDefTemplateArg)
+  taskDefRegisterCmd.Flag("container", "Container the container flags apply to.").Default(serverContainerName).StringVar(&taskDefContainerArg)
+  taskDefRegisterCmd.Flag("image-tag", "Image tag for the container.").Default("").StringVar(&taskDefImageTagArg)
+  taskDefRegisterCmd.Flag("memory", "Hard memory limit for the container in MB.").Default("0").Int64Var(&taskDefMemoryArg)
+  taskDefRegisterCmd.Flag("memory-reservation", "Memory reservation for the container in MB.").Default("0").Int64Var(&taskDefMemoryReservationArg)
+  taskDefRegisterCmd.Flag("jvm-opts", "JVM options for the server.").Default("").StringVar(&taskDefJVMOptsArg)
+  taskDefRegisterCmd.Flag("volume", "Host volume as name=/host/path, can repeat.").StringsVar(&taskDefVolumesArg)
+  taskDefRegisterCmd.Flag("efs-volume", "EFS volume as name=fs-id or name=fs-id:/root/dir, can repeat.").StringsVar(&taskDefEFSVolumesArg)
+  taskDefRegisterCmd.Flag("fargate", "Register for Fargate, with awsvpc networking.").Default("false").BoolVar(&taskDefFargateFlag)
+  taskDefRegisterCmd.Flag("task-cpu", "Task level CPU units, Fargate needs this.").Default("").StringVar(&taskDefTaskCPUArg)
+  taskDefRegisterCmd.Flag("task-memory", "Task level memory in MB, Fargate needs this.").Default("").StringVar(&taskDefTaskMemoryArg)
+  taskDefRegisterCmd.Flag("execution-role", "Task execution role, Fargate needs this to pull images and write logs.").Default("").StringVar(&taskDefExecutionRoleArg)
+  taskDefRegisterCmd.Flag("ulimit", "Ulimit as name=soft:hard, can repeat.").StringsVar(&taskDefUlimitsArg)
+  taskDefRegisterCmd.Flag("log-group", "Send container logs to this awslogs group.").Default("").StringVar(&taskDefLogGroupArg)
+  taskDefRegisterCmd.Flag("log-prefix", "awslogs stream prefix.").Default("").StringVar(&taskDefLogPrefixArg)
+  taskDefRegisterCmd.Flag("log-region", "awslogs region.").Default("").StringVar(&taskDefLogRegionArg)
+  taskDefRegisterCmd.Arg("family", "Family to register under, defaults to the family of the source.").Default("").StringVar(&taskDefFamilyArg)
+
+  taskDefDiffCmd = taskDefCmd.Command("diff", "Show the differences between two task definitions.")
+  taskDefDiffCmd.Flag("reveal", "Show secret looking environment values.").Default("false").BoolVar(&taskDefRevealFlag)
+  taskDefDiffCmd.Arg("from", "Family:revision or arn.").Required().StringVar(&taskDefArg)
+  taskDefDiffCmd.Arg("to", "Family:revision or arn.").Required().StringVar(&taskDefOtherArg)
+
+  // Fleet
+  fleetCmd = app.Command("fleet", "Context for commands across all the servers on a cluster.")
+  fleetUpgradeCmd = fleetCmd.Command("upgrade", "Restart every server on the cluster onto a new task definition, in the background.")
+  fleetUpgradeCmd.Flag("taskdef", "Task definition to upgrade to, family:revision or arn.").Required().StringVar(&fleetTaskDefArg)
+  fleetUpgradeCmd.Flag("override", "Upgrade servers even if the new task definition takes their users over quota.").Default("false").BoolVar(&quotaOverrideFlag)
+  fleetUpgradeCmd.Flag("parallel", "Number of servers to restart at a time.").Default(defaultFleetParallel).IntVar(&fleetParallelArg)
+  fleetUpgradeCmd.Flag("snapshot-timeout", "How long to wait for each server's snapshot after saving its world.").Default(defaultSnapshotTimeout).DurationVar(&snapshotTimeoutArg)
+  fleetUpgradeCmd.Flag("ready-timeout", "How long to wait for each new server to accept logins.").Default(defaultReadyTimeout).DurationVar(&readyTimeoutArg)
+  fleetUpgradeCmd.Arg("cluster", "The cluster to upgrade.").Action(setCurrent).StringVar(&clusterArg)
+  fleetStatusCmd = fleetCmd.Command("status", "Show the progress of a fleet upgrade.")
+  fleetPauseCmd = fleetCmd.Command("pause", "Pause a fleet upgrade after the current batch.")
+  fleetResumeCmd = fleetCmd.Command("resume", "Resume a paused fleet upgrade.")
+  fleetAbortCmd = fleetCmd.Command("abort", "Stop a fleet upgrade after the current batch.")
+
   setupLogs()
 }
 
@@ -250,6 +643,14 @@ func DoICommand(line string, sess *session.Session, ecsSvc *ecs.ECS, ec2Svc *ec2
 
   // This is due to a 'peculiarity' of kingpin: it collects strings as arguments across parses.
   testString = []string{}
+  taskDefVolumesArg = []string{}
+  taskDefEFSVolumesArg = []string{}
+  taskDefUlimitsArg = []string{}
+  serverSetArg = []string{}
+  envSetArg = []string{}
+  placementAttributeArg = []string{}
+  subnetArg = []string{}
+  securityGroupArg = []string{}
 
   // Prepare a line for parsing
   line = strings.TrimRight(line, "\n")
@@ -271,12 +672,21 @@ func DoICommand(line string, sess *session.Session, ecsSvc *ecs.ECS, ec2Svc *ec2
       case quit.FullCommand(): err = doQuit(sess)
 
       case envListCmd.FullCommand(): err = doListEnv(sess)
+      case envDiffCmd.FullCommand(): err = doDiffEnv(sess)
+      case envSetCmd.FullCommand(): err = doEnvSetCmd(sess)
 
-      case proxyLaunchCmd.FullCommand(): err = doLaunchProxy(proxyNameArg, currentCluster, proxyTaskDefArg, sess)
+      case proxyLaunchCmd.FullCommand(): err = doLaunchProxyCmd(sess)
       case proxyListCmd.FullCommand(): err = doListProxies(sess)
       case proxyAttachCmd.FullCommand(): err = doAttachProxy(sess)
       case proxyDNSCmd.FullCommand(): err = doListProxyDNS(sess)
-      // case proxyRemoveServerCmd.FullCommand(): err = doProxyRemoveServer(sess)
+      case proxyLogsCmd.FullCommand(): err = doProxyLogsCmd(sess)
+      case proxyServersCmd.FullCommand(): err = doProxyServersCmd(sess)
+      case proxyAccessAddCmd.FullCommand(): err = doProxyAccessAddCmd(sess)
+      case proxyAccessRmCmd.FullCommand(): err = doProxyAccessRmCmd(sess)
+      case proxyForcedHostSetCmd.FullCommand(): err = doProxyForcedHostSetCmd(sess)
+      case proxyForcedHostRmCmd.FullCommand(): err = doProxyForcedHostRmCmd(sess)
+      case proxyTerminateCmd.FullCommand(): err = doProxyTerminateCmd(sess)
+      case proxyReplaceCmd.FullCommand(): err = doProxyReplaceCmd(sess)
 
       // Cluster Commands
       case clusterListCmd.FullCommand(): err = doListClusters(sess)
@@ -287,17 +697,54 @@ func DoICommand(line string, sess *session.Session, ecsSvc *ecs.ECS, ec2Svc *ec2
       case serverLaunchCmd.FullCommand(): err = doLaunchServerCmd(sess)
       case serverStartCmd.FullCommand(): err = doStartServerCmd(sess)
       case serverRestartCmd.FullCommand(): err = doRestartServerCmd(sess)
+      case serverHibernateCmd.FullCommand(): err = doServerHibernateCmd(sess)
+      case serverWakeCmd.FullCommand(): err = doServerWakeCmd(sess)
       case serverTerminateCmd.FullCommand(): err = doTerminateServerCmd(sess)
       case serverListCmd.FullCommand(): err = doListServersCmd(currentCluster, sess)
       case serverStatusCmd.FullCommand(): err = doStatusServersCmd(currentCluster, sess)
       case serverDescribeCmd.FullCommand(): err = doDescribeServerCmd(serverNameArg, currentCluster, sess)
       case serverProxyCmd.FullCommand(): err = doServerProxyCmd(sess)
       case serverUnProxyCmd.FullCommand(): err = doServerUnProxyCmd(sess)
+      case serverMoveProxyCmd.FullCommand(): err = doServerMoveProxyCmd(sess)
+      case serverWhyCmd.FullCommand(): err = doServerWhyCmd(sess)
+      case serverLogsCmd.FullCommand(): err = doServerLogsCmd(sess)
+      case serverOpsAddCmd.FullCommand(): err = doServerOpsAddCmd(sess)
+      case serverOpsRmCmd.FullCommand(): err = doServerOpsRmCmd(sess)
+      case serverOpsListCmd.FullCommand(): err = doServerOpsListCmd(sess)
+      case serverWhitelistAddCmd.FullCommand(): err = doServerWhitelistAddCmd(sess)
+      case serverWhitelistRmCmd.FullCommand(): err = doServerWhitelistRmCmd(sess)
+      case serverWhitelistListCmd.FullCommand(): err = doServerWhitelistListCmd(sess)
+      case serverWhitelistOnCmd.FullCommand(): err = doServerWhitelistOnCmd(sess)
+      case serverWhitelistOffCmd.FullCommand(): err = doServerWhitelistOffCmd(sess)
       case dnsCmd.FullCommand(): err = doListDNS(sess)
       // case serverAttachCmd.FullCommand(): err = doServerAttachCmd(sess)
 
+      // Watchdog
+      case watchServersCmd.FullCommand(): err = doWatchServersCmd(sess)
+      case watchStatusCmd.FullCommand(): err = doWatchStatusCmd()
+      case watchStopCmd.FullCommand(): err = doWatchStopCmd()
+
+      // Costs
+      case costReportCmd.FullCommand(): err = doCostReportCmd(sess)
+
+      // Quotas
+      case quotaShowCmd.FullCommand(): err = doQuotaShowCmd(sess)
+
       // Snapshot commands
       case archiveListCmd.FullCommand(): err = doArchiveListCmd(sess)
+
+      // Fleet commands
+      case fleetUpgradeCmd.FullCommand(): err = doFleetUpgradeCmd(sess)
+      case fleetStatusCmd.FullCommand(): err = doFleetStatusCmd()
+      case fleetPauseCmd.FullCommand(): err = doFleetPauseCmd()
+      case fleetResumeCmd.FullCommand(): err = doFleetResumeCmd()
+      case fleetAbortCmd.FullCommand(): err = doFleetAbortCmd()
+
+      // Task definition commands
+      case taskDefListCmd.FullCommand(): err = doTaskDefListCmd(sess)
+      case taskDefShowCmd.FullCommand(): err = doTaskDefShowCmd(sess)
+      case taskDefRegisterCmd.FullCommand(): err = doTaskDefRegisterCmd(sess)
+      case taskDefDiffCmd.FullCommand(): err = doTaskDefDiffCmd(sess)
     }
   }
   return err
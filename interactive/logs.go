@@ -0,0 +1,183 @@
+package interactive
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "os/signal"
+  "strings"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Container logs.
+// We only know how to read the awslogs driver, where the stream name
+// is built from the stream prefix, container name and task id.
+//
+
+const (
+  serverContainerName = "minecraft"
+  controllerContainerName = "controller"
+
+  awsLogsDriver = "awslogs"
+  awsLogsGroupOption = "awslogs-group"
+  awsLogsRegionOption = "awslogs-region"
+  awsLogsPrefixOption = "awslogs-stream-prefix"
+
+  defaultLogsSince = "10m"
+  logsFollowInterval = 2 * time.Second
+
+  // Point the logs client somewhere else, eg. a local CloudWatch Logs for testing.
+  logsEndpointEnv = "ECS_CRAFT_LOGS_ENDPOINT"
+)
+
+func doServerLogsCmd(sess *session.Session) (error) {
+  s, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  container := logsContainerArg
+  if container == "" { container = serverContainerName }
+  return showContainerLogs(container, *s.TaskArn, s.DeepTask.TaskDefinition, sess)
+}
+
+func doProxyLogsCmd(sess *session.Session) (error) {
+  p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  task, err := describeTask(currentCluster, p.TaskArn, sess)
+  if err != nil { return err }
+  tdResp, err := ecs.New(sess).DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: task.TaskDefinitionArn})
+  if err != nil { return err }
+  container := logsContainerArg
+  if container == "" { container = mclib.BungeeProxyServerContainerName }
+  return showContainerLogs(container, p.TaskArn, tdResp.TaskDefinition, sess)
+}
+
+// showContainerLogs prints the logs since logsSinceArg, and keeps going
+// with --follow until interrupted.
+func showContainerLogs(container, taskArn string, td *ecs.TaskDefinition, sess *session.Session) (error) {
+  loc, err := containerLogLocation(container, taskArn, td)
+  if err != nil { return err }
+
+  stop := make(chan bool)
+  if logsFollowFlag {
+    fmt.Printf("%sFollowing %s:%s, <ctrl-C> to stop.%s\n", titleColor, loc.Group, loc.Stream, resetColor)
+    sig := make(chan os.Signal, 1)
+    done := make(chan bool)
+    signal.Notify(sig, os.Interrupt)
+    defer signal.Stop(sig)
+    defer close(done)
+    go func() {
+      select {
+      case <-sig: close(stop)
+      case <-done:
+      }
+    }()
+  }
+  return streamLogs(loc, logsEndpointArg, time.Now().Add(-logsSinceArg), logsFollowFlag, os.Stdout, stop, sess)
+}
+
+// streamLogs writes events from start on. If follow is set
+// it keeps polling for new events until stop is closed.
+func streamLogs(loc awsLogsLocation, endpoint string, start time.Time, follow bool, w io.Writer, 
+  stop <-chan bool, sess *session.Session) (error) {
+  svc := logsService(loc, endpoint, sess)
+  params := &cloudwatchlogs.GetLogEventsInput{
+    LogGroupName: aws.String(loc.Group),
+    LogStreamName: aws.String(loc.Stream),
+    StartTime: aws.Int64(start.UnixNano() / int64(time.Millisecond)),
+    StartFromHead: aws.Bool(true),
+  }
+  for {
+    resp, err := svc.GetLogEvents(params)
+    if err != nil { return err }
+    for _, e := range resp.Events {
+      t := time.Unix(0, *e.Timestamp * int64(time.Millisecond))
+      fmt.Fprintf(w, "%s %s\n", t.Local().Format(time.Stamp), strings.TrimRight(*e.Message, "\n"))
+    }
+
+    // The forward token doesn't change once we've caught up.
+    caughtUp := params.NextToken != nil && resp.NextForwardToken != nil && *params.NextToken == *resp.NextForwardToken
+    params.NextToken = resp.NextForwardToken
+    params.StartTime = nil
+    if caughtUp || len(resp.Events) == 0 {
+      if !follow { return nil }
+      select {
+      case <-stop:
+        return nil
+      case <-time.After(logsFollowInterval):
+      }
+    }
+  }
+}
+
+// Where to find the logs for one container of a task.
+type awsLogsLocation struct {
+  Container string
+  Group string
+  Region string
+  Stream string
+}
+
+// taskID is the last element of a task arn.
+func taskID(taskArn string) string {
+  parts := strings.Split(taskArn, "/")
+  return parts[len(parts)-1]
+}
+
+// containerLogLocation works out the log group and stream for a container
+// from the task definition's log configuration.
+func containerLogLocation(containerName, taskArn string, td *ecs.TaskDefinition) (loc awsLogsLocation, err error) {
+  cdef, ok := awslib.GetContainerDefinition(containerName, td)
+  if !ok || cdef == nil { return loc, fmt.Errorf("No container %s in task definition.", containerName) }
+  lc := cdef.LogConfiguration
+  if lc == nil || lc.LogDriver == nil { return loc, fmt.Errorf("No log configuration for container %s.", containerName) }
+  if *lc.LogDriver != awsLogsDriver {
+    return loc, fmt.Errorf("Container %s logs to %s, only %s is supported.", containerName, *lc.LogDriver, awsLogsDriver)
+  }
+
+  option := func(k string) string {
+    if v, ok := lc.Options[k]; ok && v != nil { return *v }
+    return ""
+  }
+  loc.Container = containerName
+  loc.Group = option(awsLogsGroupOption)
+  loc.Region = option(awsLogsRegionOption)
+  prefix := option(awsLogsPrefixOption)
+  if loc.Group == "" { return loc, fmt.Errorf("No %s option for container %s.", awsLogsGroupOption, containerName) }
+  if prefix == "" {
+    return loc, fmt.Errorf("No %s option for container %s, can't find the log stream from the task.",
+      awsLogsPrefixOption, containerName)
+  }
+  loc.Stream = fmt.Sprintf("%s/%s/%s", prefix, containerName, taskID(taskArn))
+  return loc, err
+}
+
+// logsService talks to endpoint, or if that's "" to ECS_CRAFT_LOGS_ENDPOINT, or to AWS.
+func logsService(loc awsLogsLocation, endpoint string, sess *session.Session) (*cloudwatchlogs.CloudWatchLogs) {
+  config := aws.NewConfig()
+  if loc.Region != "" { config = config.WithRegion(loc.Region) }
+  if endpoint == "" { endpoint = os.Getenv(logsEndpointEnv) }
+  if endpoint != "" { config = config.WithEndpoint(endpoint) }
+  return cloudwatchlogs.New(sess, config)
+}
+
+// lastLogEvents returns up to n of the most recent events in the container's log stream.
+func lastLogEvents(loc awsLogsLocation, endpoint string, n int64, sess *session.Session) ([]*cloudwatchlogs.OutputLogEvent, error) {
+  resp, err := logsService(loc, endpoint, sess).GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
+    LogGroupName: aws.String(loc.Group),
+    LogStreamName: aws.String(loc.Stream),
+    Limit: aws.Int64(n),
+    StartFromHead: aws.Bool(false),
+  })
+  if err != nil { return nil, err }
+  return resp.Events, nil
+}
@@ -0,0 +1,80 @@
+package interactive
+
+import (
+  "bytes"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/credentials"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestContainerLogLocation(t *testing.T) {
+  td := &ecs.TaskDefinition{
+    ContainerDefinitions: []*ecs.ContainerDefinition{
+      {
+        Name: aws.String(serverContainerName),
+        LogConfiguration: &ecs.LogConfiguration{
+          LogDriver: aws.String(awsLogsDriver),
+          Options: map[string]*string{
+            awsLogsGroupOption: aws.String("craft"),
+            awsLogsRegionOption: aws.String("us-west-2"),
+            awsLogsPrefixOption: aws.String("servers"),
+          },
+        },
+      },
+      {
+        Name: aws.String(controllerContainerName),
+        LogConfiguration: &ecs.LogConfiguration{LogDriver: aws.String("json-file")},
+      },
+    },
+  }
+  taskArn := "arn:aws:ecs:us-west-2:123456789012:task/0b69d5c0-d655-4695-98cd-5d2d526d9d5a"
+
+  loc, err := containerLogLocation(serverContainerName, taskArn, td)
+  assert.NoError(t, err)
+  assert.Equal(t, "craft", loc.Group)
+  assert.Equal(t, "us-west-2", loc.Region)
+  assert.Equal(t, "servers/minecraft/0b69d5c0-d655-4695-98cd-5d2d526d9d5a", loc.Stream)
+
+  _, err = containerLogLocation(controllerContainerName, taskArn, td)
+  assert.Error(t, err, "Expecting only awslogs to be supported.")
+}
+
+// Stands in for CloudWatch Logs, handing out one page of events then catching up.
+func TestStreamLogsFromLocalEndpoint(t *testing.T) {
+  calls := 0
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    assert.Equal(t, "Logs_20140328.GetLogEvents", r.Header.Get("X-Amz-Target"))
+    calls++
+    resp := map[string]interface{}{"nextForwardToken": "f/2", "nextBackwardToken": "b/1", "events": []interface{}{}}
+    if calls == 1 {
+      now := time.Now().UnixNano() / int64(time.Millisecond)
+      resp["events"] = []interface{}{
+        map[string]interface{}{"timestamp": now, "message": "Starting minecraft server", "ingestionTime": now},
+        map[string]interface{}{"timestamp": now, "message": "Done (12.5s)! For help, type \"help\"", "ingestionTime": now},
+      }
+    }
+    w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+    json.NewEncoder(w).Encode(resp)
+  }))
+  defer ts.Close()
+
+  sess := session.New(aws.NewConfig().WithRegion("us-east-1").
+    WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+
+  loc := awsLogsLocation{Container: serverContainerName, Group: "craft", Stream: "servers/minecraft/task"}
+  out := new(bytes.Buffer)
+  stop := make(chan bool)
+  close(stop)
+  err := streamLogs(loc, ts.URL, time.Now().Add(-time.Minute), true, out, stop, sess)
+  assert.NoError(t, err)
+  assert.Contains(t, out.String(), "Starting minecraft server")
+  assert.Contains(t, out.String(), "Done (12.5s)!")
+  assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,263 @@
+package interactive
+
+import (
+  "fmt"
+  "os"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/cloudwatch"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Per server metrics.
+// CPU and memory come from CloudWatch: the instance from AWS/EC2 (and CWAgent for memory),
+// the task from Container Insights: per task with enhanced observability on, otherwise
+// only per task definition family, which is labelled as such.
+// Reservations come from the container instance itself.
+// TPS and heap come over RCON, when the server has a plugin that reports them.
+//
+
+const (
+  defaultMetricsWindow = "1h"
+  metricsPeriod = 5 * 60 // seconds
+
+  notAvailable = "<not-available>"
+)
+
+type metricSummary struct {
+  Name string
+  Unit string
+  Current float64
+  Min float64
+  Avg float64
+  Max float64
+  Points int
+}
+
+// summarizeDatapoints folds CloudWatch datapoints into
+// the latest average, and min/avg/max over all of them.
+func summarizeDatapoints(name, unit string, dps []*cloudwatch.Datapoint) (ms metricSummary) {
+  ms.Name = name
+  ms.Unit = unit
+  ms.Points = len(dps)
+  if len(dps) == 0 { return ms }
+
+  sort.Sort(byTimestamp(dps))
+  var sum float64
+  ms.Min = *dps[0].Minimum
+  ms.Max = *dps[0].Maximum
+  for _, dp := range dps {
+    if *dp.Minimum < ms.Min { ms.Min = *dp.Minimum }
+    if *dp.Maximum > ms.Max { ms.Max = *dp.Maximum }
+    sum += *dp.Average
+  }
+  ms.Avg = sum / float64(len(dps))
+  ms.Current = *dps[len(dps)-1].Average
+  return ms
+}
+
+type byTimestamp []*cloudwatch.Datapoint
+func (d byTimestamp) Len() int { return len(d) }
+func (d byTimestamp) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d byTimestamp) Less(i, j int) bool { return d[i].Timestamp.Before(*d[j].Timestamp) }
+
+func (ms metricSummary) values() (current, min, avg, max string) {
+  if ms.Points == 0 { return notAvailable, "", "", "" }
+  f := func(v float64) string { return fmt.Sprintf("%.1f%s", v, ms.Unit) }
+  return f(ms.Current), f(ms.Min), f(ms.Avg), f(ms.Max)
+}
+
+func getMetric(namespace, metric string, dims map[string]string, window time.Duration,
+  sess *session.Session) ([]*cloudwatch.Datapoint, error) {
+  dimensions := make([]*cloudwatch.Dimension, 0)
+  for k, v := range dims {
+    dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(k), Value: aws.String(v)})
+  }
+  now := time.Now()
+  resp, err := cloudwatch.New(sess).GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+    Namespace: aws.String(namespace),
+    MetricName: aws.String(metric),
+    Dimensions: dimensions,
+    StartTime: aws.Time(now.Add(-window)),
+    EndTime: aws.Time(now),
+    Period: aws.Int64(metricsPeriod),
+    Statistics: aws.StringSlice([]string{
+      cloudwatch.StatisticAverage, cloudwatch.StatisticMinimum, cloudwatch.StatisticMaximum,
+    }),
+  })
+  if err != nil { return nil, err }
+  return resp.Datapoints, nil
+}
+
+func describeContainerInstance(clusterName, containerInstanceArn string, sess *session.Session) (*ecs.ContainerInstance, error) {
+  resp, err := ecs.New(sess).DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+    Cluster: aws.String(clusterName),
+    ContainerInstances: []*string{aws.String(containerInstanceArn)},
+  })
+  if err != nil { return nil, err }
+  if len(resp.ContainerInstances) == 0 { return nil, fmt.Errorf("No container instance %s.", containerInstanceArn) }
+  return resp.ContainerInstances[0], nil
+}
+
+// resourceValue finds an integer resource, CPU or MEMORY, in a container instance resource list.
+func resourceValue(resources []*ecs.Resource, name string) int64 {
+  for _, r := range resources {
+    if r.Name != nil && *r.Name == name && r.IntegerValue != nil {
+      return *r.IntegerValue
+    }
+  }
+  return 0
+}
+
+// Family from a task definition arn: arn:...:task-definition/minecraft:12 => minecraft
+func taskDefinitionFamily(tdArn string) string {
+  family := taskID(tdArn)
+  if i := strings.LastIndex(family, ":"); i >= 0 {
+    family = family[:i]
+  }
+  return family
+}
+
+func printServerMetrics(s *mclib.Server, clusterName string, window time.Duration, sess *session.Session) {
+  dt := s.DeepTask
+  fmt.Printf("\n%sMetrics (last %s):%s\n", titleColor, window, resetColor)
+
+  summaries := make([]metricSummary, 0)
+  family := taskDefinitionFamily(*dt.TaskDefinition.TaskDefinitionArn)
+  familyDims := map[string]string{"ClusterName": clusterName, "TaskDefinitionFamily": family}
+  taskDims := map[string]string{"ClusterName": clusterName, "TaskDefinitionFamily": family, "TaskId": taskID(*dt.Task.TaskArn)}
+  for _, m := range []struct{ name, metric, unit string }{
+    {"Task CPU", "CpuUtilized", "u"},
+    {"Task Memory", "MemoryUtilized", "MB"},
+  } {
+    name := m.name
+    dps, err := getMetric("ECS/ContainerInsights", m.metric, taskDims, window, sess)
+    if err != nil { log.Debug(fmt.Sprintf("Failed to get %s: %s", m.metric, err)) }
+    if len(dps) == 0 {
+      name = fmt.Sprintf("%s (all %s tasks)", m.name, family)
+      dps, err = getMetric("ECS/ContainerInsights", m.metric, familyDims, window, sess)
+      if err != nil { log.Debug(fmt.Sprintf("Failed to get %s for %s: %s", m.metric, family, err)) }
+    }
+    summaries = append(summaries, summarizeDatapoints(name, m.unit, dps))
+  }
+
+  var ci *ecs.ContainerInstance
+  if dt.Task.ContainerInstanceArn != nil {
+    var err error
+    ci, err = describeContainerInstance(clusterName, *dt.Task.ContainerInstanceArn, sess)
+    if err != nil { fmt.Printf("%sFailed to describe container instance: %s%s\n", warnColor, err, resetColor) }
+  }
+  if ci != nil && ci.Ec2InstanceId != nil {
+    instanceDims := map[string]string{"InstanceId": *ci.Ec2InstanceId}
+    dps, err := getMetric("AWS/EC2", "CPUUtilization", instanceDims, window, sess)
+    if err != nil { log.Debug(fmt.Sprintf("Failed to get instance CPU: %s", err)) }
+    summaries = append(summaries, summarizeDatapoints("Instance CPU", "%", dps))
+    dps, err = getMetric("CWAgent", "mem_used_percent", instanceDims, window, sess)
+    if err != nil { log.Debug(fmt.Sprintf("Failed to get instance memory: %s", err)) }
+    summaries = append(summaries, summarizeDatapoints("Instance Memory", "%", dps))
+  }
+
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sMetric\tCurrent\tMin\tAvg\tMax%s\n", titleColor, resetColor)
+  for _, ms := range summaries {
+    current, min, avg, max := ms.values()
+    fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s%s\n", nullColor, ms.Name, current, min, avg, max, resetColor)
+  }
+  w.Flush()
+
+  // Reservations on the instance.
+  if ci != nil {
+    fmt.Printf("\n%sInstance Reservation:%s\n", titleColor, resetColor)
+    w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+    fmt.Fprintf(w, "%sInstance\tResource\tRegistered\tRemaining\tReserved%s\n", titleColor, resetColor)
+    for _, r := range []string{"CPU", "MEMORY"} {
+      registered := resourceValue(ci.RegisteredResources, r)
+      remaining := resourceValue(ci.RemainingResources, r)
+      reserved := notAvailable
+      if registered > 0 { reserved = fmt.Sprintf("%.1f%%", 100*float64(registered-remaining)/float64(registered)) }
+      fmt.Fprintf(w, "%s%s\t%s\t%d\t%d\t%s%s\n", nullColor, aws.StringValue(ci.Ec2InstanceId), r,
+        registered, remaining, reserved, resetColor)
+    }
+    w.Flush()
+  }
+
+  // JVM, if the server will tell us.
+  tps, heap := jvmStats(s)
+  fmt.Printf("\n%sJVM:%s\n", titleColor, resetColor)
+  w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sTPS (1m, 5m, 15m)\tHeap\tJVM Opts%s\n", titleColor, resetColor)
+  env, _ := s.ServerEnvironment()
+  jvmOpts := env[mclib.JVMOptsKey]
+  if jvmOpts == "" { jvmOpts = "<default>" }
+  fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", nullColor, tps, heap, jvmOpts, resetColor)
+  w.Flush()
+}
+
+// jvmStats asks the server for TPS and memory over RCON.
+// Vanilla doesn't report these, spigot/paper have tps and essentials has gc.
+func jvmStats(s *mclib.Server) (tps, heap string) {
+  tps, heap = notAvailable, notAvailable
+  rc, err := dialServerRcon(s)
+  if err != nil { return tps, heap }
+  defer rc.Close()
+
+  if resp, err := rc.Command("tps"); err == nil {
+    if v, ok := parseTPS(resp); ok {
+      parts := make([]string, len(v))
+      for i, f := range v { parts[i] = strconv.FormatFloat(f, 'f', 1, 64) }
+      tps = strings.Join(parts, ", ")
+    }
+  }
+  if resp, err := rc.Command("gc"); err == nil {
+    if used, max, ok := parseHeap(resp); ok {
+      heap = fmt.Sprintf("%d/%d MB", used, max)
+    }
+  }
+  return tps, heap
+}
+
+var (
+  formattingCodes = regexp.MustCompile("§.")
+  tpsLine = regexp.MustCompile(`TPS from last[^:]*:\s*([\d.*,\s]+)`)
+  heapMax = regexp.MustCompile(`(?i)maximum memory:\s*([\d,]+)\s*MB`)
+  heapFree = regexp.MustCompile(`(?i)free memory:\s*([\d,]+)\s*MB`)
+  heapAllocated = regexp.MustCompile(`(?i)allocated memory:\s*([\d,]+)\s*MB`)
+)
+
+// parseTPS reads spigot's "TPS from last 1m, 5m, 15m: 20.0, 19.8, *20.0"
+func parseTPS(resp string) (tps []float64, ok bool) {
+  m := tpsLine.FindStringSubmatch(formattingCodes.ReplaceAllString(resp, ""))
+  if m == nil { return tps, false }
+  for _, f := range strings.Split(m[1], ",") {
+    v, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(f), "*"), 64)
+    if err != nil { return nil, false }
+    tps = append(tps, v)
+  }
+  return tps, len(tps) > 0
+}
+
+// parseHeap reads essentials' gc output for used and maximum heap in MB.
+func parseHeap(resp string) (used, max int64, ok bool) {
+  resp = formattingCodes.ReplaceAllString(resp, "")
+  num := func(re *regexp.Regexp) (int64, bool) {
+    m := re.FindStringSubmatch(resp)
+    if m == nil { return 0, false }
+    v, err := strconv.ParseInt(strings.Replace(m[1], ",", "", -1), 10, 64)
+    return v, err == nil
+  }
+  max, okMax := num(heapMax)
+  allocated, okAlloc := num(heapAllocated)
+  free, okFree := num(heapFree)
+  if !okMax || !okAlloc || !okFree { return 0, 0, false }
+  return allocated - free, max, true
+}
@@ -0,0 +1,47 @@
+package interactive
+
+import (
+  "testing"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/cloudwatch"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestParseTPS(t *testing.T) {
+  tps, ok := parseTPS("§6TPS from last 1m, 5m, 15m: §a20.0, §a19.5, §a*20.0")
+  assert.True(t, ok)
+  assert.Equal(t, []float64{20.0, 19.5, 20.0}, tps)
+
+  _, ok = parseTPS("Unknown command. Type \"/help\" for help.")
+  assert.False(t, ok)
+}
+
+func TestParseHeap(t *testing.T) {
+  resp := "§6Uptime:§c 2 hours\n§6Maximum memory:§c 2,048 MB.\n§6Allocated memory:§c 1,024 MB.\n§6Free memory:§c 256 MB."
+  used, max, ok := parseHeap(resp)
+  assert.True(t, ok)
+  assert.Equal(t, int64(768), used)
+  assert.Equal(t, int64(2048), max)
+}
+
+func TestSummarizeDatapoints(t *testing.T) {
+  now := time.Now()
+  dps := []*cloudwatch.Datapoint{
+    {Timestamp: aws.Time(now), Average: aws.Float64(30), Minimum: aws.Float64(20), Maximum: aws.Float64(50)},
+    {Timestamp: aws.Time(now.Add(-10 * time.Minute)), Average: aws.Float64(10), Minimum: aws.Float64(5), Maximum: aws.Float64(15)},
+  }
+  ms := summarizeDatapoints("cpu", "%", dps)
+  assert.Equal(t, 30.0, ms.Current, "Expecting current to be the latest average.")
+  assert.Equal(t, 5.0, ms.Min)
+  assert.Equal(t, 20.0, ms.Avg)
+  assert.Equal(t, 50.0, ms.Max)
+
+  current, _, _, _ := summarizeDatapoints("cpu", "%", nil).values()
+  assert.Equal(t, notAvailable, current)
+}
+
+func TestTaskDefinitionFamily(t *testing.T) {
+  assert.Equal(t, "minecraft", taskDefinitionFamily("arn:aws:ecs:us-east-1:123456789012:task-definition/minecraft:12"))
+  assert.Equal(t, "minecraft", taskDefinitionFamily("minecraft:12"))
+}
@@ -0,0 +1,171 @@
+package interactive
+
+import (
+  "fmt"
+  "strings"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Placement of server and proxy tasks.
+// We run the tasks ourselves, rather than through awslib.RunTaskWithEnv
+// or ServerSpec.LaunchServer, so we can set constraints, strategy and group.
+// Survival servers and proxies go in their own task groups and,
+// unless asked to colocate, stay off each other's instances.
+//
+
+const (
+  survivalTaskGroup = "craft-survival"
+  proxyTaskGroup = "craft-proxy"
+
+  spreadStrategy = "spread"
+  binpackStrategy = "binpack"
+)
+
+// placement is where to put a task, the zero value is the default placement.
+type placement struct {
+  Instance string      // EC2 instance id.
+  AZ string
+  Attributes []string  // Container instance attributes as name=value.
+  Strategy string      // "", spread or binpack.
+  Colocate bool        // Drop the anti-affinity between proxies and survival servers.
+  Scale bool           // Grow the cluster if there's no room.
+  ScaleTimeout time.Duration
+  LaunchType string    // "" or ec2 for container instances, or fargate.
+  Subnets []string     // Fargate's awsvpc networking.
+  SecurityGroups []string
+}
+
+// placementFromArgs collects the placement flags.
+func placementFromArgs() (pl placement, err error) {
+  if spreadFlag && binpackFlag { return pl, fmt.Errorf("Choose one of --spread or --binpack.") }
+  pl = placement{
+    Instance: placementInstanceArg,
+    AZ: placementAZArg,
+    Attributes: placementAttributeArg,
+    Colocate: colocateFlag,
+    Scale: scaleFlag,
+    ScaleTimeout: scaleTimeoutArg,
+    LaunchType: launchTypeArg,
+    Subnets: subnetArg,
+    SecurityGroups: securityGroupArg,
+  }
+  if spreadFlag { pl.Strategy = spreadStrategy }
+  if binpackFlag { pl.Strategy = binpackStrategy }
+  if pl.fargate() { err = pl.validateFargate() }
+  return pl, err
+}
+
+// constraints for a task in group, which should avoid the instances running tasks in avoid.
+func (pl placement) constraints(avoid string) (cs []*ecs.PlacementConstraint, err error) {
+  memberOf := func(expr string) {
+    cs = append(cs, &ecs.PlacementConstraint{
+      Type: aws.String(ecs.PlacementConstraintTypeMemberOf),
+      Expression: aws.String(expr),
+    })
+  }
+  if pl.Instance != "" {
+    if !strings.HasPrefix(pl.Instance, "i-") { return cs, fmt.Errorf("%s isn't an EC2 instance id.", pl.Instance) }
+    memberOf("ec2InstanceId == " + pl.Instance)
+  }
+  if pl.AZ != "" { memberOf("attribute:ecs.availability-zone == " + pl.AZ) }
+  for _, a := range pl.Attributes {
+    kv := strings.SplitN(a, "=", 2)
+    if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+      return cs, fmt.Errorf("Bad attribute %q, expected name=value.", a)
+    }
+    memberOf(fmt.Sprintf("attribute:%s == %s", kv[0], kv[1]))
+  }
+  if avoid != "" && !pl.Colocate { memberOf(fmt.Sprintf("not(task:group == %s)", avoid)) }
+  return cs, nil
+}
+
+func (pl placement) strategy() (ss []*ecs.PlacementStrategy) {
+  switch pl.Strategy {
+  case spreadStrategy:
+    ss = append(ss,
+      &ecs.PlacementStrategy{Type: aws.String(ecs.PlacementStrategyTypeSpread), Field: aws.String("attribute:ecs.availability-zone")},
+      &ecs.PlacementStrategy{Type: aws.String(ecs.PlacementStrategyTypeSpread), Field: aws.String("instanceId")})
+  case binpackStrategy:
+    ss = append(ss,
+      &ecs.PlacementStrategy{Type: aws.String(ecs.PlacementStrategyTypeBinpack), Field: aws.String("memory")})
+  }
+  return ss
+}
+
+// runTaskInput launches taskDef with the environment overrides in group, placed by pl.
+func (pl placement) runTaskInput(clusterName, taskDef string, env awslib.ContainerEnvironmentMap,
+  group, avoid string) (*ecs.RunTaskInput, error) {
+  cs, err := pl.constraints(avoid)
+  if err != nil { return nil, err }
+  // Fargate tasks don't share hosts, and don't take constraints.
+  if pl.fargate() { cs = nil }
+
+  overrides := make([]*ecs.ContainerOverride, 0)
+  for _, c := range sortedContainers(containerEnvs(env)) {
+    kvs := make([]*ecs.KeyValuePair, 0)
+    for _, k := range sortedFlatKeys(env[c]) {
+      kvs = append(kvs, &ecs.KeyValuePair{Name: aws.String(k), Value: aws.String(env[c][k])})
+    }
+    overrides = append(overrides, &ecs.ContainerOverride{Name: aws.String(c), Environment: kvs})
+  }
+
+  in := &ecs.RunTaskInput{
+    Cluster: aws.String(clusterName),
+    TaskDefinition: aws.String(taskDef),
+    Count: aws.Int64(1),
+    Overrides: &ecs.TaskOverride{ContainerOverrides: overrides},
+    PlacementStrategy: pl.strategy(),
+  }
+  if len(cs) > 0 { in.PlacementConstraints = cs }
+  if pl.fargate() {
+    in.LaunchType = aws.String(ecs.LaunchTypeFargate)
+    in.NetworkConfiguration = pl.networkConfiguration()
+    in.PlacementStrategy = nil
+  }
+  if group != "" { in.Group = aws.String(group) }
+  return in, nil
+}
+
+func runTaskWithPlacement(clusterName, taskDef string, env awslib.ContainerEnvironmentMap,
+  group, avoid string, pl placement, sess *session.Session) (*ecs.RunTaskOutput, error) {
+  in, err := pl.runTaskInput(clusterName, taskDef, env, group, avoid)
+  if err != nil { return nil, err }
+  return ecs.New(sess).RunTask(in)
+}
+
+// runServerTask does what ServerSpec.LaunchServer does, with placement.
+func runServerTask(ss mclib.ServerSpec, pl placement, sess *session.Session) (s *mclib.Server, err error) {
+  group, avoid := serverTaskGroups(ss.ServerContainerEnv())
+  if err = ensureCapacity(ss.ClusterName, ss.TaskDefinition, avoid, pl, sess); err != nil { return s, err }
+  resp, err := runTaskWithPlacement(ss.ClusterName, ss.TaskDefinition, ss.ContainerEnv, group, avoid, pl, sess)
+  if err != nil { return s, err }
+  if len(resp.Failures) > 0 {
+    printECSFailures(ss.ClusterName, resp.Failures)
+    return s, fmt.Errorf("Received %d failures on launch.", len(resp.Failures))
+  }
+  if len(resp.Tasks) != 1 {
+    printTaskList(resp.Tasks)
+    return s, fmt.Errorf("Expected one new server task, got %d.", len(resp.Tasks))
+  }
+  return mclib.GetServer(ss.ClusterName, *resp.Tasks[0].TaskArn, sess)
+}
+
+// serverTaskGroups puts survival servers, the heavy ones, in their own group away from the proxies.
+// Minecraft defaults to survival.
+func serverTaskGroups(serverEnv map[string]string) (group, avoid string) {
+  switch serverEnv[mclib.ModeKey] {
+  case "", "survival", "0":
+    return survivalTaskGroup, proxyTaskGroup
+  }
+  return group, avoid
+}
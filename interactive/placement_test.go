@@ -0,0 +1,78 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+func expressions(t *testing.T, pl placement, avoid string) (exprs []string) {
+  cs, err := pl.constraints(avoid)
+  assert.NoError(t, err)
+  for _, c := range cs { exprs = append(exprs, *c.Expression) }
+  return exprs
+}
+
+func TestPlacementConstraints(t *testing.T) {
+  assert.Empty(t, expressions(t, placement{}, ""))
+  assert.Equal(t, []string{"not(task:group == craft-proxy)"}, expressions(t, placement{}, proxyTaskGroup))
+  assert.Empty(t, expressions(t, placement{Colocate: true}, proxyTaskGroup))
+
+  pl := placement{Instance: "i-0abc", AZ: "us-east-1a", Attributes: []string{"size=large", "gpu=no=really"}}
+  assert.Equal(t, []string{
+    "ec2InstanceId == i-0abc",
+    "attribute:ecs.availability-zone == us-east-1a",
+    "attribute:size == large",
+    "attribute:gpu == no=really",
+  }, expressions(t, pl, ""))
+
+  _, err := placement{Instance: "0abc"}.constraints("")
+  assert.Error(t, err)
+  for _, a := range []string{"size", "=large", "size="} {
+    _, err := placement{Attributes: []string{a}}.constraints("")
+    assert.Error(t, err, "Expecting %q to be rejected", a)
+  }
+}
+
+func TestPlacementRunTaskInput(t *testing.T) {
+  env := awslib.ContainerEnvironmentMap{
+    "minecraft": {"B": "2", "A": "1"},
+    "controller": {"C": "3"},
+  }
+  in, err := placement{Strategy: binpackStrategy}.runTaskInput("craft", "td:1", env, survivalTaskGroup, proxyTaskGroup)
+  if assert.NoError(t, err) {
+    assert.Equal(t, "craft-survival", *in.Group)
+    assert.Len(t, in.PlacementConstraints, 1)
+    if assert.Len(t, in.PlacementStrategy, 1) { assert.Equal(t, "binpack", *in.PlacementStrategy[0].Type) }
+    overrides := in.Overrides.ContainerOverrides
+    if assert.Len(t, overrides, 2) {
+      assert.Equal(t, "controller", *overrides[0].Name)
+      assert.Equal(t, "minecraft", *overrides[1].Name)
+      assert.Equal(t, "A", *overrides[1].Environment[0].Name)
+      assert.Equal(t, "2", *overrides[1].Environment[1].Value)
+    }
+  }
+
+  in, err = placement{Strategy: spreadStrategy}.runTaskInput("craft", "td:1", env, "", "")
+  if assert.NoError(t, err) {
+    assert.Nil(t, in.Group)
+    assert.Nil(t, in.PlacementConstraints)
+    assert.Len(t, in.PlacementStrategy, 2)
+  }
+}
+
+func TestServerTaskGroups(t *testing.T) {
+  for _, mode := range []string{"", "survival", "0"} {
+    group, avoid := serverTaskGroups(map[string]string{mclib.ModeKey: mode})
+    assert.Equal(t, survivalTaskGroup, group, "Mode %q", mode)
+    assert.Equal(t, proxyTaskGroup, avoid, "Mode %q", mode)
+  }
+  group, avoid := serverTaskGroups(map[string]string{mclib.ModeKey: "creative"})
+  assert.Empty(t, group)
+  assert.Empty(t, avoid)
+}
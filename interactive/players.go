@@ -0,0 +1,346 @@
+package interactive
+
+import (
+  "crypto/md5"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "os"
+  "sort"
+  "strings"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Operators and whitelist.
+// Changes go to a running server over RCON, and into a local
+// store so that they go into the server's environment the next time
+// it's launched, started or restarted.
+//
+
+const (
+  playersFile = "./.ecs-craft_players.json"
+
+  whitelistKey = "WHITELIST"
+  enableWhitelistKey = "ENABLE_WHITELIST"
+
+  mojangLookupName = "mojang"
+  offlineLookupName = "offline"
+  mojangProfileURL = "https://api.mojang.com/users/profiles/minecraft/"
+  lookupTimeout = 10 * time.Second
+)
+
+type player struct {
+  Name string `json:"name"`
+  UUID string `json:"uuid"`
+}
+
+// playerLookup resolves a player name to the player's canonical name and UUID.
+type playerLookup interface {
+  Lookup(name string) (player, error)
+}
+
+func newPlayerLookup(kind string) (playerLookup) {
+  if kind == offlineLookupName { return offlineLookup{} }
+  return mojangLookup{URL: mojangProfileURL}
+}
+
+// mojangLookup asks Mojang's profile API.
+type mojangLookup struct {
+  URL string
+}
+
+func (ml mojangLookup) Lookup(name string) (p player, err error) {
+  client := &http.Client{Timeout: lookupTimeout}
+  resp, err := client.Get(ml.URL + name)
+  if err != nil { return p, err }
+  defer resp.Body.Close()
+  if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound {
+    return p, fmt.Errorf("No player named %q.", name)
+  }
+  if resp.StatusCode != http.StatusOK {
+    return p, fmt.Errorf("Player lookup for %q failed: %s", name, resp.Status)
+  }
+  profile := struct {
+    ID string `json:"id"`
+    Name string `json:"name"`
+  }{}
+  if err = json.NewDecoder(resp.Body).Decode(&profile); err != nil { return p, err }
+  return player{Name: profile.Name, UUID: dashedUUID(profile.ID)}, nil
+}
+
+// offlineLookup is the local stub, it gives the UUID an offline mode server
+// would use, without asking anyone.
+type offlineLookup struct{}
+
+func (ol offlineLookup) Lookup(name string) (player, error) {
+  return player{Name: name, UUID: offlineUUID(name)}, nil
+}
+
+// offlineUUID is java's UUID.nameUUIDFromBytes("OfflinePlayer:" + name).
+func offlineUUID(name string) string {
+  b := md5.Sum([]byte("OfflinePlayer:" + name))
+  b[6] = (b[6] & 0x0f) | 0x30
+  b[8] = (b[8] & 0x3f) | 0x80
+  return dashedUUID(hex.EncodeToString(b[:]))
+}
+
+func dashedUUID(id string) string {
+  if len(id) != 32 { return id }
+  return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
+
+//
+// The local store.
+//
+
+type serverPlayers struct {
+  Ops []player `json:"ops"`
+  Whitelist []player `json:"whitelist"`
+  WhitelistOn bool `json:"whitelistOn"`
+}
+
+// Keyed by cluster/user/server, server names are only unique per user.
+// Entries from before the user was in the key are cluster/server, and
+// move to the new key the first time they're asked for with a user.
+type playerStore map[string]*serverPlayers
+
+func playerStoreKey(clusterName, userName, serverName string) string {
+  return clusterName + "/" + userName + "/" + serverName
+}
+
+func loadPlayerStore(fileName string) (ps playerStore, err error) {
+  ps = make(playerStore)
+  data, err := ioutil.ReadFile(fileName)
+  if os.IsNotExist(err) { return ps, nil }
+  if err != nil { return ps, err }
+  err = json.Unmarshal(data, &ps)
+  return ps, err
+}
+
+func (ps playerStore) save(fileName string) (error) {
+  data, err := json.MarshalIndent(ps, "", "  ")
+  if err != nil { return err }
+  return ioutil.WriteFile(fileName, data, 0600)
+}
+
+func (ps playerStore) server(clusterName, userName, serverName string) (*serverPlayers) {
+  key := playerStoreKey(clusterName, userName, serverName)
+  sp, ok := ps[key]
+  if !ok {
+    legacy := clusterName + "/" + serverName
+    if sp, ok = ps[legacy]; ok {
+      delete(ps, legacy)
+    } else {
+      sp = &serverPlayers{}
+    }
+    ps[key] = sp
+  }
+  return sp
+}
+
+// users are the users with players stored for a server of that name.
+func (ps playerStore) users(clusterName, serverName string) (users []string) {
+  prefix, suffix := clusterName + "/", "/" + serverName
+  for key := range ps {
+    if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) { continue }
+    if user := strings.TrimSuffix(strings.TrimPrefix(key, prefix), suffix); user != "" && !strings.Contains(user, "/") {
+      users = append(users, user)
+    }
+  }
+  sort.Strings(users)
+  return users
+}
+
+// Adds or replaces by UUID.
+func addPlayer(players []player, p player) []player {
+  for i, op := range players {
+    if op.UUID == p.UUID {
+      players[i] = p
+      return players
+    }
+  }
+  return append(players, p)
+}
+
+func removePlayer(players []player, name string) ([]player, bool) {
+  for i, p := range players {
+    if strings.EqualFold(p.Name, name) || p.UUID == name {
+      return append(players[:i], players[i+1:]...), true
+    }
+  }
+  return players, false
+}
+
+func playerNames(players []player) string {
+  names := make([]string, len(players))
+  for i, p := range players { names[i] = p.Name }
+  return strings.Join(names, ",")
+}
+
+// environment is what goes into the minecraft container for these players.
+func (sp *serverPlayers) environment() (map[string]string) {
+  env := make(map[string]string)
+  if len(sp.Ops) > 0 { env[mclib.OpsKey] = playerNames(sp.Ops) }
+  if sp.WhitelistOn {
+    env[enableWhitelistKey] = "true"
+    if len(sp.Whitelist) > 0 { env[whitelistKey] = playerNames(sp.Whitelist) }
+  }
+  return env
+}
+
+// playerSettings are the stored ops and whitelist for a server, as environment settings.
+func playerSettings(clusterName, userName, serverName string) (map[string]string) {
+  ps, err := loadPlayerStore(playersFile)
+  if err != nil {
+    fmt.Printf("%sCouldn't read ops and whitelist from %s: %s%s\n", warnColor, playersFile, err, resetColor)
+    return map[string]string{}
+  }
+  return ps.server(clusterName, userName, serverName).environment()
+}
+
+// playersUser is the user for the server on the command line: --user, the running
+// server's, or the only one with players stored for that name.
+func playersUser(ps playerStore, s *mclib.Server) (string, error) {
+  if playerUserArg != "" { return playerUserArg, nil }
+  if s != nil { return s.User, nil }
+  users := ps.users(currentCluster, serverNameArg)
+  if len(users) == 1 { return users[0], nil }
+  if len(users) == 0 { return "", fmt.Errorf("%s isn't running, use --user to say whose it is.", serverNameArg) }
+  return "", fmt.Errorf("%s isn't running and %s all have one, use --user to say whose it is.",
+    serverNameArg, strings.Join(users, ", "))
+}
+
+//
+// Commands
+//
+
+func doServerOpsAddCmd(sess *session.Session) (error) {
+  p, err := newPlayerLookup(playerLookupArg).Lookup(playerNameArg)
+  if err != nil { return err }
+  return changePlayers(func(sp *serverPlayers) error {
+    sp.Ops = addPlayer(sp.Ops, p)
+    return nil
+  }, "op " + p.Name, sess)
+}
+
+func doServerOpsRmCmd(sess *session.Session) (error) {
+  return changePlayers(func(sp *serverPlayers) (err error) {
+    var ok bool
+    if sp.Ops, ok = removePlayer(sp.Ops, playerNameArg); !ok {
+      return fmt.Errorf("%s is not an op on %s.", playerNameArg, serverNameArg)
+    }
+    return nil
+  }, "deop " + playerNameArg, sess)
+}
+
+func doServerWhitelistAddCmd(sess *session.Session) (error) {
+  p, err := newPlayerLookup(playerLookupArg).Lookup(playerNameArg)
+  if err != nil { return err }
+  return changePlayers(func(sp *serverPlayers) error {
+    sp.Whitelist = addPlayer(sp.Whitelist, p)
+    return nil
+  }, "whitelist add " + p.Name, sess)
+}
+
+func doServerWhitelistRmCmd(sess *session.Session) (error) {
+  return changePlayers(func(sp *serverPlayers) (err error) {
+    var ok bool
+    if sp.Whitelist, ok = removePlayer(sp.Whitelist, playerNameArg); !ok {
+      return fmt.Errorf("%s is not on the whitelist for %s.", playerNameArg, serverNameArg)
+    }
+    return nil
+  }, "whitelist remove " + playerNameArg, sess)
+}
+
+func doServerWhitelistOnCmd(sess *session.Session) (error) {
+  return changePlayers(func(sp *serverPlayers) error {
+    sp.WhitelistOn = true
+    return nil
+  }, "whitelist on", sess)
+}
+
+func doServerWhitelistOffCmd(sess *session.Session) (error) {
+  return changePlayers(func(sp *serverPlayers) error {
+    sp.WhitelistOn = false
+    return nil
+  }, "whitelist off", sess)
+}
+
+func doServerOpsListCmd(sess *session.Session) (error) {
+  sp, _, err := storedPlayers(sess)
+  if err != nil { return err }
+  fmt.Printf("%sOps for %s:%s\n", titleColor, serverNameArg, resetColor)
+  printPlayers(sp.Ops)
+  return nil
+}
+
+func doServerWhitelistListCmd(sess *session.Session) (error) {
+  sp, s, err := storedPlayers(sess)
+  if err != nil { return err }
+  state := "off"
+  if sp.WhitelistOn { state = "on" }
+  fmt.Printf("%sWhitelist for %s (%s):%s\n", titleColor, serverNameArg, state, resetColor)
+  printPlayers(sp.Whitelist)
+
+  // And what the server itself thinks, if it's up.
+  if s == nil { return nil }
+  if resp, err := serverRconCommand(s, "whitelist list"); err == nil {
+    fmt.Printf("%sServer says: %s%s\n", nullColor, formattingCodes.ReplaceAllString(resp, ""), resetColor)
+  }
+  return nil
+}
+
+// storedPlayers are the players for the server on the command line, and the server if it's running.
+func storedPlayers(sess *session.Session) (sp *serverPlayers, s *mclib.Server, err error) {
+  ps, err := loadPlayerStore(playersFile)
+  if err != nil { return sp, s, err }
+  s, err = getServer(serverNameArg, currentCluster, sess)
+  if err != nil { s = nil }
+  user, err := playersUser(ps, s)
+  if err != nil { return sp, s, err }
+  return ps.server(currentCluster, user, serverNameArg), s, nil
+}
+
+func printPlayers(players []player) {
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sPlayer\tUUID%s\n", titleColor, resetColor)
+  for _, p := range players {
+    fmt.Fprintf(w, "%s%s\t%s%s\n", nullColor, p.Name, p.UUID, resetColor)
+  }
+  w.Flush()
+}
+
+// changePlayers records the change in the store and then makes it on the server,
+// if the server is running.
+func changePlayers(change func(*serverPlayers) error, rconCmd string, sess *session.Session) (error) {
+  ps, err := loadPlayerStore(playersFile)
+  if err != nil { return err }
+  s, serr := getServer(serverNameArg, currentCluster, sess)
+  if serr != nil { s = nil }
+  user, err := playersUser(ps, s)
+  if err != nil { return err }
+  if err = change(ps.server(currentCluster, user, serverNameArg)); err != nil { return err }
+  if err = ps.save(playersFile); err != nil { return err }
+
+  if s == nil {
+    fmt.Printf("%sSaved. %s isn't running (%s), the change will apply when it next starts.%s\n",
+      warnColor, serverNameArg, serr, resetColor)
+    return nil
+  }
+  resp, err := serverRconCommand(s, rconCmd)
+  if err != nil {
+    fmt.Printf("%sSaved, but couldn't change the running server, the change will apply on restart: %s%s\n",
+      warnColor, err, resetColor)
+    return nil
+  }
+  fmt.Printf("%s%s: %s%s\n", successColor, serverNameArg, formattingCodes.ReplaceAllString(resp, ""), resetColor)
+  return nil
+}
@@ -0,0 +1,89 @@
+package interactive
+
+import (
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "testing"
+  "github.com/stretchr/testify/assert"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+func TestOfflineUUID(t *testing.T) {
+  p, err := offlineLookup{}.Lookup("Notch")
+  assert.NoError(t, err)
+  assert.Equal(t, "b50ad385-829d-3141-a216-7e7d7539ba7f", p.UUID)
+}
+
+func TestMojangLookup(t *testing.T) {
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/notch" {
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    fmt.Fprint(w, `{"id":"069a79f444e94726a5befca90e38aaf5","name":"Notch"}`)
+  }))
+  defer ts.Close()
+
+  ml := mojangLookup{URL: ts.URL + "/"}
+  p, err := ml.Lookup("notch")
+  assert.NoError(t, err)
+  assert.Equal(t, player{Name: "Notch", UUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5"}, p)
+
+  _, err = ml.Lookup("nobody")
+  assert.Error(t, err)
+}
+
+func TestPlayerStore(t *testing.T) {
+  dir, err := ioutil.TempDir("", "players")
+  assert.NoError(t, err)
+  defer os.RemoveAll(dir)
+  fileName := filepath.Join(dir, "players.json")
+
+  ps, err := loadPlayerStore(fileName)
+  assert.NoError(t, err)
+  assert.Len(t, ps, 0)
+
+  sp := ps.server("minecraft", "jdr", "world")
+  sp.Ops = addPlayer(sp.Ops, player{Name: "alice", UUID: "a"})
+  sp.Whitelist = addPlayer(sp.Whitelist, player{Name: "alice", UUID: "a"})
+  sp.Whitelist = addPlayer(sp.Whitelist, player{Name: "bob", UUID: "b"})
+  sp.Whitelist = addPlayer(sp.Whitelist, player{Name: "Bob", UUID: "b"})
+  assert.NoError(t, ps.save(fileName))
+
+  ps, err = loadPlayerStore(fileName)
+  assert.NoError(t, err)
+  sp = ps.server("minecraft", "jdr", "world")
+  assert.Len(t, sp.Whitelist, 2)
+  assert.Equal(t, map[string]string{mclib.OpsKey: "alice"}, sp.environment())
+
+  sp.WhitelistOn = true
+  assert.Equal(t, map[string]string{mclib.OpsKey: "alice", whitelistKey: "alice,Bob", enableWhitelistKey: "true"},
+    sp.environment())
+
+  var ok bool
+  sp.Whitelist, ok = removePlayer(sp.Whitelist, "BOB")
+  assert.True(t, ok)
+  _, ok = removePlayer(sp.Whitelist, "carol")
+  assert.False(t, ok)
+  assert.Equal(t, "alice", playerNames(sp.Whitelist))
+}
+
+func TestPlayerStoreMigration(t *testing.T) {
+  ps := playerStore{"minecraft/world": &serverPlayers{Ops: []player{{Name: "alice", UUID: "a"}}}}
+  assert.Empty(t, ps.users("minecraft", "world"))
+
+  sp := ps.server("minecraft", "jdr", "world")
+  assert.Equal(t, "alice", playerNames(sp.Ops))
+  _, ok := ps["minecraft/world"]
+  assert.False(t, ok)
+
+  ps.server("minecraft", "bob", "world")
+  ps.server("minecraft", "bob", "world2")
+  assert.Equal(t, []string{"bob", "jdr"}, ps.users("minecraft", "world"))
+}
@@ -2,11 +2,14 @@ package interactive
 
 import(
   "fmt"
+  "net"
   "os"
+  "sort"
   "strings"
   "time"
   "text/tabwriter"
   "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
 
   // "mclib"
   "github.com/jdrivas/mclib"
@@ -20,16 +23,34 @@ func doListProxies(sess *session.Session) (error) {
     fmt.Printf("%s%s proxies on %s%s\n", titleColor, 
       time.Now().Local().Format(time.RFC1123), currentCluster, resetColor)
     w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
-    fmt.Fprintf(w, "%sName\tProxy Public Addr\tRcon Private Addr\tStatus\tUptime\tServers\tARN%s\n", titleColor, resetColor)
+    eips, eerr := instanceEIPs(sess)
+    if eerr != nil { log.Debug(fmt.Sprintf("Failed to get elastic IPs: %s", eerr)) }
+    fmt.Fprintf(w, "%sName\tProxy Public Addr\tEIP\tRcon Private Addr\tStatus\tUptime\tServers\tShared\tARN%s\n", titleColor, resetColor)
     if len(proxies) == 0 {
       fmt.Fprintf(w,"%s\tNO PROXIES FOUND ON THIS CLUSTER\n%s", titleColor, resetColor)
     } else {
+      // Servers that more than one proxy can reach.
+      names := make(map[string][]string)
+      serverCount := make(map[string]int)
       for _, p := range proxies {
-        serverNames, _ := p.ServerNames()
+        names[p.TaskArn], _ = p.ServerNames()
+        for _, n := range names[p.TaskArn] { serverCount[n]++ }
+      }
+      for _, p := range proxies {
+        serverNames := names[p.TaskArn]
+        shared := make([]string, 0)
+        for _, n := range serverNames {
+          if serverCount[n] > 1 { shared = append(shared, n) }
+        }
         dt := dtm[p.TaskArn]
-        fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
-          p.Name, p.PublicIpAddress(), p.RconAddress(), dt.LastStatus(), dt.UptimeString(), 
-          strings.Join(serverNames, ", "), awslib.ShortArnString(&p.TaskArn), resetColor)
+        fillProxyAddress(p, currentCluster, sess)
+        eip := "<none>"
+        if id := dt.GetInstanceID(); id != nil {
+          if a, ok := eips[*id]; ok { eip = *a.PublicIp }
+        }
+        fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
+          p.Name, p.PublicIpAddress(), eip, p.RconAddress(), dt.LastStatus(), dt.UptimeString(), 
+          strings.Join(serverNames, ", "), strings.Join(shared, ", "), awslib.ShortArnString(&p.TaskArn), resetColor)
       }
     }
     w.Flush()
@@ -39,6 +60,11 @@ func doListProxies(sess *session.Session) (error) {
 func doAttachProxy(sess *session.Session) (error) {
   p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
   if err == nil {
+    err = waitForProxyReady(p, currentCluster, readyTimeoutArg, sess)
+    if err != nil { return fmt.Errorf("Proxy is %s. Not attaching to the network.", err) }
+    p, err = withProxyEIP(p, currentCluster, proxyEIPArg, sess)
+    if err != nil { return fmt.Errorf("Failed to bind elastic IP %s, not attaching to the network: %s", proxyEIPArg, err) }
+    fillProxyAddress(p, currentCluster, sess)
     domainName, changeInfo, err := p.AttachToNetwork()
     if err == nil {
       status := "----"
@@ -59,8 +85,28 @@ func doAttachProxy(sess *session.Session) (error) {
 }
 
 
+func doLaunchProxyCmd(sess *session.Session) (error) {
+  pl, err := placementFromArgs()
+  if err != nil { return err }
+  return doLaunchProxy(proxyNameArg, currentCluster, proxyTaskDefArg, proxyEIPArg, pl, sess)
+}
+
+func doLaunchProxy(proxyName, clusterName, proxyTD, eip string, pl placement, sess *session.Session) (error) {
+  tasks, err := runProxyTask(proxyName, clusterName, proxyTD, pl, sess)
+  if err != nil { return err }
+
+  if len(tasks) == 1 {
+    setUpProxyWaitAlerts(clusterName, *tasks[0].TaskArn, eip, sess)
+  } else {
+    fmt.Printf("%sGot more tasks in response to the launch than expected.%s\n", warnColor, resetColor)
+    printTaskList(tasks)
+    fmt.Printf("%sNo more updates forthcomming.%s\n", warnColor, resetColor)
+  }
+  return nil
+}
+
 // TODO: Much of this needs to move to mclib.
-func doLaunchProxy(proxyName, clusterName, proxyTD string, sess *session.Session) (error) {
+func runProxyTask(proxyName, clusterName, proxyTD string, pl placement, sess *session.Session) (tasks []*ecs.Task, err error) {
 
   // Get these from the UI for now.
   // TODO: want to do some form of config for this,
@@ -73,13 +119,18 @@ func doLaunchProxy(proxyName, clusterName, proxyTD string, sess *session.Session
   // clusterName := currentCluster
 
   env := getProxyTaskEnvironment(proxyName,DefaultArchiveRegion,bucketName, clusterName)
+  hubEnv := env[mclib.BungeeProxyHubServerContainerName]
+  err = setRconSecret(hubEnv, clusterName, serverSecretKind, proxyName, hubEnv[mclib.ServerNameKey], sess)
+  if err != nil { return tasks, err }
+
+  if err = ensureCapacity(clusterName, proxyTaskDef, survivalTaskGroup, pl, sess); err != nil { return tasks, err }
   start := time.Now()
-  resp, err := awslib.RunTaskWithEnv(clusterName, proxyTaskDef, env, sess)
-  if err != nil { return err }
+  resp, err := runTaskWithPlacement(clusterName, proxyTaskDef, env, proxyTaskGroup, survivalTaskGroup, pl, sess)
+  if err != nil { return tasks, err }
 
   if len(resp.Failures) > 0 {
     printECSFailures(clusterName, resp.Failures)
-    return fmt.Errorf("Received %d failiures on launch.", len(resp.Failures))
+    return tasks, fmt.Errorf("Received %d failiures on launch.", len(resp.Failures))
   }
 
   proxyEnv := env[mclib.BungeeProxyServerContainerName]
@@ -93,15 +144,7 @@ func doLaunchProxy(proxyName, clusterName, proxyTD string, sess *session.Session
     contEnv[mclib.ArchiveRegionKey], contEnv[mclib.ArchiveBucketKey], resetColor)
   w.Flush()
 
-  tasks := resp.Tasks
-  if len(tasks) == 1 {
-    setUpProxyWaitAlerts(clusterName, *tasks[0].TaskArn, sess)
-  } else {
-    fmt.Printf("%sGot more tasks in response to the launch than expected.%s\n", warnColor, resetColor)
-    printTaskList(tasks)
-    fmt.Printf("%sNo more updates forthcomming.%s\n", warnColor, resetColor)
-  }
-  return nil
+  return resp.Tasks, nil
 }
 
 // This implements logic to support using a default taskdef with either Random or 
@@ -138,7 +181,7 @@ func getProxyTaskEnvironment(proxyName, region, bucketName, clusterName string)
     mclib.ClusterNameKey: clusterName,
     mclib.RoleKey: mclib.CraftProxyRole,
     mclib.ServerNameKey: proxyName,
-    mclib.RconPasswordKey: mclib.ProxyRconPasswordDefault,
+    mclib.RconPasswordKey: mclib.ProxyRconPasswordDefault,  // mclib's proxy calls only know this one.
     "AWS_REGION": region,
   }
 
@@ -162,7 +205,6 @@ func getProxyTaskEnvironment(proxyName, region, bucketName, clusterName string)
     mclib.QueryPortKey: mclib.ProxyHubQueryPortDefault,
     mclib.EnableRconKey: mclib.ProxyHubEnableRconDefault,
     mclib.RconPortKey: mclib.ProxyHubRconPortDefault,
-    mclib.RconPasswordKey: mclib.ProxyHubRconPasswordDefault, // TODO NO NO NO NO NO NO NO NO NO NO NO NO NO
     mclib.MOTDKey: fmt.Sprintf("The gateway to %s.", proxyName),
     mclib.PVPKey: mclib.ProxyHubPVPDefault,
     mclib.LevelKey: mclib.ProxyHubLevelDefault,
@@ -182,4 +224,129 @@ func getProxyTaskEnvironment(proxyName, region, bucketName, clusterName string)
   }
 
   return cenv
-}
\ No newline at end of file
+}
+
+// waitForProxyReady blocks until bungee is accepting connections.
+func waitForProxyReady(p *mclib.Proxy, clusterName string, timeout time.Duration, sess *session.Session) (error) {
+  task, err := describeTask(clusterName, p.TaskArn, sess)
+  if err != nil { return err }
+  td, err := getTaskDefinition(*task.TaskDefinitionArn, sess)
+  if err != nil { return err }
+  probes, err := proxyProbes(p, task, td)
+  if err != nil { return err }
+  return waitForReady(probes, timeout)
+}
+
+// serverProxy returns the first proxy that proxies the server, or nil.
+func serverProxy(s *mclib.Server, proxies []*mclib.Proxy) (*mclib.Proxy) {
+  for _, p := range proxies {
+    if proxied, err := p.IsServerProxied(s); err == nil && proxied {
+      return p
+    }
+  }
+  return nil
+}
+
+//
+// Fine grained routing.
+// Access is the proxy's server map, the forced host sends the server's
+// FQDN to that server. Neither touches DNS.
+//
+
+func doProxyServersCmd(sess *session.Session) (error) {
+  p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  names, err := p.ServerNames()
+  if err != nil { return err }
+  servers, err := getServers(currentCluster, sess)
+  if err != nil { return err }
+  byName := make(map[string]*mclib.Server)
+  for _, s := range servers { byName[s.Name] = s }
+  hasAccess := make(map[string]bool)
+
+  fmt.Printf("%s%s servers on %s:%s\n", titleColor, p.Name, currentCluster, resetColor)
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sServer\tTask\tAddress\tForced Host\tStatus%s\n", titleColor, resetColor)
+  sort.Strings(names)
+  for _, name := range names {
+    hasAccess[name] = true
+    s, ok := byName[name]
+    if !ok {
+      fmt.Fprintf(w, "%s%s\t\t\t\t%s%s\n", failColor, name, "access to a server that isn't running", resetColor)
+      continue
+    }
+    color, status, fqdn := successColor, "proxied", "<none>"
+    proxied, err := p.IsServerProxied(s)
+    if err != nil {
+      color, status = failColor, fmt.Sprintf("error: %s", err)
+    } else if !proxied {
+      color, status = warnColor, "access only, no forced host"
+    }
+    if proxied {
+      if dn, err := p.ProxiedServerFQDN(s); err == nil { fqdn = dn }
+    }
+    fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s%s\n", color, name, awslib.ShortArnString(s.TaskArn),
+      net.JoinHostPort(s.PrivateServerIp, s.ServerPort), fqdn, status, resetColor)
+  }
+
+  // Forced hosts for servers the proxy doesn't know how to reach.
+  for _, s := range servers {
+    if hasAccess[s.Name] { continue }
+    if proxied, err := p.IsServerProxied(s); err == nil && proxied {
+      fqdn, _ := p.ProxiedServerFQDN(s)
+      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s%s\n", failColor, s.Name, awslib.ShortArnString(s.TaskArn),
+        net.JoinHostPort(s.PrivateServerIp, s.ServerPort), fqdn, "forced host, but no access", resetColor)
+    }
+  }
+  w.Flush()
+  return nil
+}
+
+func doProxyAccessAddCmd(sess *session.Session) (error) {
+  p, s, err := proxyAndServer(proxyNameArg, serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  if err = p.AddServerAccess(s); err != nil { return err }
+  fmt.Printf("%s%s can now reach %s.%s\n", successColor, p.Name, s.Name, resetColor)
+  return nil
+}
+
+// The server needn't be running, access is by name and outlives it.
+func doProxyAccessRmCmd(sess *session.Session) (error) {
+  p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  has, err := proxyHasServer(p, serverNameArg)
+  if err != nil { return err }
+  if !has { return fmt.Errorf("%s doesn't have access to %s.", p.Name, serverNameArg) }
+  s, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil {
+    fmt.Printf("%s%s isn't running, removing it from %s by name.%s\n", warnColor, serverNameArg, p.Name, resetColor)
+    s = &mclib.Server{Name: serverNameArg, ClusterName: currentCluster}
+  }
+  if err = p.RemoveServerAccess(s); err != nil { return err }
+  fmt.Printf("%sRemoved %s from %s.%s\n", successColor, s.Name, p.Name, resetColor)
+  return nil
+}
+
+func doProxyForcedHostSetCmd(sess *session.Session) (error) {
+  p, s, err := proxyAndServer(proxyNameArg, serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  if err = p.StartProxyForServer(s); err != nil { return err }
+  fqdn, _ := p.ProxiedServerFQDN(s)
+  fmt.Printf("%s%s now forwards %s to %s.%s\n", successColor, p.Name, fqdn, s.Name, resetColor)
+  return nil
+}
+
+func doProxyForcedHostRmCmd(sess *session.Session) (error) {
+  p, s, err := proxyAndServer(proxyNameArg, serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  if err = p.StopProxyForServer(s); err != nil { return err }
+  fmt.Printf("%sRemoved the forced host for %s from %s.%s\n", successColor, s.Name, p.Name, resetColor)
+  return nil
+}
+
+func proxyAndServer(proxyName, serverName, clusterName string, sess *session.Session) (p *mclib.Proxy, s *mclib.Server, err error) {
+  p, err = mclib.GetProxyFromName(proxyName, clusterName, sess)
+  if err != nil { return p, s, err }
+  s, err = getServer(serverName, clusterName, sess)
+  return p, s, err
+}
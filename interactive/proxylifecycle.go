@@ -0,0 +1,452 @@
+package interactive
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/aws/aws-sdk-go/service/route53"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Stopping and replacing proxies.
+//
+
+// proxiedServers are the running servers the proxy has access to,
+// and the names it has access to that aren't running.
+func proxiedServers(p *mclib.Proxy, clusterName string, sess *session.Session) (servers []*mclib.Server, missing []string, err error) {
+  names, err := p.ServerNames()
+  if err != nil { return servers, missing, err }
+  all, err := getServers(clusterName, sess)
+  if err != nil { return servers, missing, err }
+  byName := make(map[string]*mclib.Server)
+  for _, s := range all { byName[s.Name] = s }
+  sort.Strings(names)
+  for _, name := range names {
+    if s, ok := byName[name]; ok {
+      servers = append(servers, s)
+    } else {
+      missing = append(missing, name)
+    }
+  }
+  return servers, missing, err
+}
+
+func doProxyTerminateCmd(sess *session.Session) (err error) {
+  p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  var to *mclib.Proxy
+  if proxyMigrateToArg != "" {
+    if to, err = mclib.GetProxyFromName(proxyMigrateToArg, currentCluster, sess); err != nil { return err }
+  }
+
+  servers, missing, err := proxiedServers(p, currentCluster, sess)
+  if err != nil { return err }
+  for _, name := range missing {
+    fmt.Printf("%s%s has access to %s, which isn't running. Ignoring it, proxy access rm will remove it.%s\n",
+      warnColor, p.Name, name, resetColor)
+  }
+
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+
+  failed := 0
+  for _, s := range servers {
+    // Shared servers stay with the other proxies.
+    if len(withoutProxy(serverProxies(s, proxies), p)) > 0 {
+      err = removeServerFromProxy(p, s, proxies, sess)
+    } else if to != nil {
+      err = moveServerProxy(s, p, to, nil, sess)
+    } else {
+      err = unproxyServer(p, s, sess)
+    }
+    if err != nil {
+      failed++
+      fmt.Printf("%sFailed to take %s off %s: %s%s\n", failColor, s.Name, p.Name, err, resetColor)
+    } else {
+      fmt.Printf("%s%s is off %s.%s\n", successColor, s.Name, p.Name, resetColor)
+    }
+  }
+  if failed > 0 && !proxyForceFlag {
+    return fmt.Errorf("%d servers are still on %s, not terminating it. Use --force to terminate anyway.", failed, p.Name)
+  }
+
+  records, err := p.DNSRecords()
+  if err != nil { return fmt.Errorf("Failed to get DNS for %s, not terminated: %s", p.Name, err) }
+  if len(records) > 0 {
+    ci, err := deleteDNSRecords(records, fmt.Sprintf("Terminating proxy %s.", p.Name), sess)
+    if err != nil { return fmt.Errorf("Failed to remove DNS for %s, not terminated: %s", p.Name, err) }
+    setAlertOnDnsChangeSync(ci, sess)
+  }
+
+  expectStop(p.TaskArn)
+  if _, err = awslib.StopTask(currentCluster, p.TaskArn, sess); err != nil { return err }
+  fmt.Printf("%sProxy %s terminated (%s).%s\n", successColor, p.Name, awslib.ShortArnString(&p.TaskArn), resetColor)
+  return nil
+}
+
+// doProxyReplaceCmd launches a new proxy with the same name, gives it all of the
+// old one's servers, moves DNS over and then stops the old task.
+func doProxyReplaceCmd(sess *session.Session) (err error) {
+  old, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  _, err = replaceProxy(old, proxyTaskDefArg, currentCluster, readyTimeoutArg, sess)
+  return err
+}
+
+// What a proxy gives its servers: access, and the forced host for the ones it's proxying.
+type proxyAccess struct {
+  Servers []string
+  Forced map[string]bool
+}
+
+// proxyAccessOf reads the proxy's access and forced hosts, checking forced hosts on the running servers.
+func proxyAccessOf(p *mclib.Proxy, servers []*mclib.Server) (pa proxyAccess, err error) {
+  pa.Forced = make(map[string]bool)
+  names, err := p.ServerNames()
+  if err != nil { return pa, err }
+  byName := make(map[string]*mclib.Server)
+  for _, s := range servers { byName[s.Name] = s }
+  sort.Strings(names)
+  for _, name := range names {
+    pa.Servers = append(pa.Servers, name)
+    s, ok := byName[name]
+    if !ok { continue }
+    proxied, err := p.IsServerProxied(s)
+    if err != nil { return pa, err }
+    pa.Forced[name] = proxied
+  }
+  return pa, nil
+}
+
+// replaceProxy does the work of proxy replace, td defaults to the old proxy's task definition.
+// Returns the new proxy.
+func replaceProxy(old *mclib.Proxy, td, clusterName string, readyTimeout time.Duration, sess *session.Session) (p *mclib.Proxy, err error) {
+  oldTask, err := describeTask(clusterName, old.TaskArn, sess)
+  if err != nil { return p, err }
+  if td == "" { td = *oldTask.TaskDefinitionArn }
+  servers, err := getServers(clusterName, sess)
+  if err != nil { return p, err }
+  access, err := proxyAccessOf(old, servers)
+  if err != nil { return p, err }
+
+  // Take the old proxy's elastic IP, if it had one.
+  eip := ""
+  if oldInstance, err := proxyInstanceID(old, clusterName, sess); err == nil {
+    eips, err := instanceEIPs(sess)
+    if err != nil { return p, err }
+    if a, ok := eips[oldInstance]; ok { eip = *a.AllocationId }
+  }
+
+  p, err = rebuildProxy(proxyRebuild{
+    Name: old.Name,
+    Old: old,
+    TaskDef: td,
+    Placement: carriedPlacement(oldTask, sess),
+    Access: access,
+    EIP: eip,
+    Cluster: clusterName,
+    ReadyTimeout: readyTimeout,
+  }, sess)
+  if err != nil { return p, fmt.Errorf("%s Old proxy left running.", err) }
+
+  expectStop(old.TaskArn)
+  if _, err = awslib.StopTask(clusterName, old.TaskArn, sess); err != nil {
+    return p, fmt.Errorf("Failed to stop the old proxy task. Everything else seemed to work: %s", err)
+  }
+  fmt.Printf("%sProxy %s replaced: %s => %s.%s\n", successColor, p.Name,
+    awslib.ShortArnString(&old.TaskArn), awslib.ShortArnString(&p.TaskArn), resetColor)
+  return p, nil
+}
+
+// What it takes to bring up a proxy in place of another: replace, or the watchdog after a crash.
+type proxyRebuild struct {
+  Name string
+  Old *mclib.Proxy          // The proxy being replaced, running or not.
+  TaskDef string
+  Placement placement
+  Access proxyAccess        // To replay on the new proxy.
+  EIP string                // Elastic IP allocation id to move to the new proxy, if any.
+  Cluster string
+  ReadyTimeout time.Duration
+}
+
+// rebuildProxy launches the new proxy, waits for it, replays access and forced hosts,
+// takes the elastic IP and moves DNS for the proxy and its servers over to it.
+// If any of that fails the new task is stopped.
+func rebuildProxy(rb proxyRebuild, sess *session.Session) (p *mclib.Proxy, err error) {
+  all, err := getServers(rb.Cluster, sess)
+  if err != nil { return p, err }
+  byName := make(map[string]*mclib.Server)
+  for _, s := range all { byName[s.Name] = s }
+  servers := make([]*mclib.Server, 0)
+  for _, name := range rb.Access.Servers {
+    if s, ok := byName[name]; ok {
+      servers = append(servers, s)
+    } else {
+      fmt.Printf("%s%s had access to %s, which isn't running. It won't be carried over.%s\n",
+        warnColor, rb.Name, name, resetColor)
+    }
+  }
+
+  tasks, err := runProxyTask(rb.Name, rb.Cluster, rb.TaskDef, rb.Placement, sess)
+  if err != nil { return p, err }
+
+  // Don't leave a half set up proxy running under the same name as the old one.
+  defer func() {
+    if err == nil { return }
+    for _, t := range tasks {
+      expectStop(*t.TaskArn)
+      if _, serr := awslib.StopTask(rb.Cluster, *t.TaskArn, sess); serr != nil {
+        err = fmt.Errorf("%s Failed to stop the new proxy task %s: %s.", err, awslib.ShortArnString(t.TaskArn), serr)
+      } else {
+        fmt.Printf("%sStopped the new proxy task %s.%s\n", warnColor, awslib.ShortArnString(t.TaskArn), resetColor)
+      }
+    }
+  }()
+
+  if len(tasks) != 1 {
+    printTaskList(tasks)
+    return p, fmt.Errorf("Expected one new proxy task, got %d.", len(tasks))
+  }
+  p, err = waitForProxyTask(rb.Cluster, *tasks[0].TaskArn, rb.ReadyTimeout, sess)
+  if err != nil { return p, err }
+
+  // Replay access and forced hosts.
+  for _, s := range servers {
+    if err = p.AddServerAccess(s); err != nil {
+      return p, fmt.Errorf("Failed to add %s to the new proxy: %s", s.Name, err)
+    }
+    if rb.Access.Forced[s.Name] {
+      if err = p.StartProxyForServer(s); err != nil {
+        return p, fmt.Errorf("Failed to set the forced host for %s on the new proxy: %s", s.Name, err)
+      }
+    }
+    fmt.Printf("%s%s carried over.%s\n", successColor, s.Name, resetColor)
+  }
+
+  if p, err = withProxyEIP(p, rb.Cluster, rb.EIP, sess); err != nil {
+    return p, fmt.Errorf("Failed to move elastic IP %s to the new proxy: %s", rb.EIP, err)
+  }
+
+  // Swing DNS for the proxy, and the servers.
+  domainName, ci, err := p.AttachToNetwork()
+  if err != nil { return p, fmt.Errorf("Failed to move DNS to the new proxy: %s", err) }
+  fmt.Printf("%s%s => %s%s\n", successColor, domainName, p.PublicProxyIp, resetColor)
+  setAlertOnDnsChangeSync(ci, sess)
+  proxies, _, err := mclib.GetProxies(rb.Cluster, sess)
+  if err != nil { return p, fmt.Errorf("Failed to get proxies: %s", err) }
+  for _, s := range servers {
+    if !rb.Access.Forced[s.Name] { continue }
+
+    // Shared servers get the new proxy in place of the old one, alongside the others.
+    others := withoutProxy(serverProxies(s, proxies), p)
+    if rb.Old != nil { others = withoutProxy(others, rb.Old) }
+    if len(others) > 0 {
+      serving := append(others, p)
+      fqdn, err := sharedServerFQDN(s, serving)
+      if err == nil { err = publishServerRecords(fqdn, serving, sess) }
+      if err != nil { return p, fmt.Errorf("Failed to move DNS for %s: %s", s.Name, err) }
+      continue
+    }
+
+    fqdn, ci, err := p.AttachToProxyNetwork(s)
+    if err != nil { return p, fmt.Errorf("Failed to move DNS for %s: %s", s.Name, err) }
+    fmt.Printf("%s%s => %s%s\n", successColor, fqdn, p.PublicProxyIp, resetColor)
+    setAlertOnDnsChangeSync(ci, sess)
+  }
+  return p, nil
+}
+
+// waitForProxyTask waits for a proxy task to run and bungee to accept connections.
+func waitForProxyTask(clusterName, taskArn string, timeout time.Duration, sess *session.Session) (p *mclib.Proxy, err error) {
+  fmt.Printf("%sWaiting for the new proxy to start.%s\n", warnColor, resetColor)
+  err = ecs.New(sess).WaitUntilTasksRunning(&ecs.DescribeTasksInput{
+    Cluster: aws.String(clusterName),
+    Tasks: []*string{aws.String(taskArn)},
+  })
+  if err != nil { return p, fmt.Errorf("New proxy task didn't start: %s.", err) }
+  p, err = mclib.GetProxy(clusterName, taskArn, sess)
+  if err != nil { return p, err }
+  fillProxyAddress(p, clusterName, sess)
+  fmt.Printf("%sNew proxy running, waiting for it to accept connections.%s\n", warnColor, resetColor)
+  if err = waitForProxyReady(p, clusterName, timeout, sess); err != nil {
+    return p, fmt.Errorf("New proxy is %s.", err)
+  }
+  return p, nil
+}
+
+// moveServerProxy gives the server to another proxy, checking each step.
+// The server's DNS is pointed at the new proxy with a single UPSERT before
+// the old proxy lets go, so the server always has a record. A server shared
+// with others keeps their records, with to's in place of from's.
+func moveServerProxy(s *mclib.Server, from, to *mclib.Proxy, others []*mclib.Proxy, sess *session.Session) (err error) {
+  step := func(name string, do func() error, check func() (bool, error)) error {
+    if err := do(); err != nil { return fmt.Errorf("Couldn't %s: %s", name, err) }
+    ok, err := check()
+    if err != nil { return fmt.Errorf("Couldn't check %s: %s", name, err) }
+    if !ok { return fmt.Errorf("Tried to %s, but it didn't take.", name) }
+    fmt.Printf("%s%s: %s, done.%s\n", successColor, s.Name, name, resetColor)
+    return nil
+  }
+
+  oldFQDN, _ := from.ProxiedServerFQDN(s)
+  oldRecord, err := findDNSRecord(from, oldFQDN)
+  if err != nil { return err }
+
+  err = step(fmt.Sprintf("add access on %s", to.Name), func() error { return to.AddServerAccess(s) },
+    func() (bool, error) { return proxyHasServer(to, s.Name) })
+  if err != nil { return err }
+
+  err = step(fmt.Sprintf("set the forced host on %s", to.Name), func() error { return to.StartProxyForServer(s) },
+    func() (bool, error) { return to.IsServerProxied(s) })
+  if err != nil { return err }
+
+  newFQDN := ""
+  if len(others) > 0 {
+    serving := append(append([]*mclib.Proxy{}, others...), to)
+    if newFQDN, err = sharedServerFQDN(s, serving); err != nil { return err }
+    err = step(fmt.Sprintf("share %s between %s", newFQDN, strings.Join(proxyNames(serving), ", ")),
+      func() error { return publishServerRecords(newFQDN, serving, sess) },
+      func() (bool, error) { return hasServerRecord(newFQDN, to, sess) })
+  } else {
+    if newFQDN, err = to.ProxiedServerFQDN(s); err != nil { return err }
+    var ttl int64 = defaultServerDNSTTL
+    if oldRecord != nil && oldRecord.TTL != nil { ttl = *oldRecord.TTL }
+    err = step(fmt.Sprintf("point %s at %s", newFQDN, to.PublicProxyIp),
+      func() error { return upsertARecord(newFQDN, to.PublicProxyIp, ttl, fmt.Sprintf("Moving %s to %s.", s.Name, to.Name), sess) },
+      func() (bool, error) {
+        r, err := findDNSRecord(to, newFQDN)
+        return r != nil, err
+      })
+  }
+  if err != nil { return err }
+
+  err = step(fmt.Sprintf("remove the forced host from %s", from.Name), func() error { return from.StopProxyForServer(s) },
+    func() (bool, error) {
+      proxied, err := from.IsServerProxied(s)
+      return !proxied, err
+    })
+  if err != nil { return err }
+
+  err = step(fmt.Sprintf("remove access from %s", from.Name), func() error { return from.RemoveServerAccess(s) },
+    func() (bool, error) {
+      has, err := proxyHasServer(from, s.Name)
+      return !has, err
+    })
+  if err != nil { return err }
+
+  // The FQDN can depend on the proxy, if so the old one goes. Shared records were replaced above.
+  if len(others) == 0 && oldRecord != nil && !sameDNSName(oldFQDN, newFQDN) {
+    ci, err := deleteDNSRecords([]*route53.ResourceRecordSet{oldRecord}, fmt.Sprintf("Moved %s to %s.", s.Name, to.Name), sess)
+    if err != nil { return fmt.Errorf("Moved, but failed to remove the old DNS record %s: %s", oldFQDN, err) }
+    setAlertOnDnsChangeSync(ci, sess)
+  }
+  return nil
+}
+
+const defaultServerDNSTTL = 60
+
+func proxyHasServer(p *mclib.Proxy, serverName string) (bool, error) {
+  names, err := p.ServerNames()
+  if err != nil { return false, err }
+  for _, n := range names {
+    if n == serverName { return true, nil }
+  }
+  return false, nil
+}
+
+// findDNSRecord finds the proxy's record for name, or nil.
+func findDNSRecord(p *mclib.Proxy, name string) (*route53.ResourceRecordSet, error) {
+  if name == "" { return nil, nil }
+  records, err := p.DNSRecords()
+  if err != nil { return nil, err }
+  for _, r := range records {
+    if r.Name != nil && sameDNSName(*r.Name, name) { return r, nil }
+  }
+  return nil, nil
+}
+
+func sameDNSName(a, b string) bool {
+  return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// upsertARecord points name at ip and waits for the change to be in sync.
+func upsertARecord(name, ip string, ttl int64, comment string, sess *session.Session) (error) {
+  svc := route53.New(sess)
+  zones, err := hostedZones(svc)
+  if err != nil { return err }
+  zone := recordZone(name, zones)
+  if zone == nil { return fmt.Errorf("No hosted zone for %s.", name) }
+  resp, err := svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+    HostedZoneId: zone.Id,
+    ChangeBatch: &route53.ChangeBatch{
+      Comment: aws.String(comment),
+      Changes: []*route53.Change{{
+        Action: aws.String(route53.ChangeActionUpsert),
+        ResourceRecordSet: &route53.ResourceRecordSet{
+          Name: aws.String(name),
+          Type: aws.String(route53.RRTypeA),
+          TTL: aws.Int64(ttl),
+          ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(ip)}},
+        },
+      }},
+    },
+  })
+  if err != nil { return err }
+  return svc.WaitUntilResourceRecordSetsChanged(&route53.GetChangeInput{Id: resp.ChangeInfo.Id})
+}
+
+func hostedZones(svc *route53.Route53) (zones []*route53.HostedZone, err error) {
+  err = svc.ListHostedZonesPages(&route53.ListHostedZonesInput{}, func(resp *route53.ListHostedZonesOutput, last bool) bool {
+    zones = append(zones, resp.HostedZones...)
+    return true
+  })
+  return zones, err
+}
+
+// deleteDNSRecords removes the records from whichever hosted zones they're in.
+func deleteDNSRecords(records []*route53.ResourceRecordSet, comment string, sess *session.Session) (ci *route53.ChangeInfo, err error) {
+  svc := route53.New(sess)
+  zones, err := hostedZones(svc)
+  if err != nil { return ci, err }
+
+  changes := make(map[string][]*route53.Change)
+  for _, r := range records {
+    zone := recordZone(*r.Name, zones)
+    if zone == nil { return ci, fmt.Errorf("No hosted zone for %s.", *r.Name) }
+    changes[*zone.Id] = append(changes[*zone.Id], &route53.Change{
+      Action: aws.String(route53.ChangeActionDelete),
+      ResourceRecordSet: r,
+    })
+  }
+  for zoneID, cs := range changes {
+    resp, err := svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+      HostedZoneId: aws.String(zoneID),
+      ChangeBatch: &route53.ChangeBatch{Changes: cs, Comment: aws.String(comment)},
+    })
+    if err != nil { return ci, err }
+    ci = resp.ChangeInfo
+  }
+  return ci, nil
+}
+
+// recordZone is the zone with the longest name that the record is in.
+func recordZone(name string, zones []*route53.HostedZone) (zone *route53.HostedZone) {
+  name = strings.TrimSuffix(name, ".") + "."
+  for _, z := range zones {
+    zn := strings.TrimSuffix(*z.Name, ".") + "."
+    if name != zn && !strings.HasSuffix(name, "." + zn) { continue }
+    if zone == nil || len(zn) > len(*zone.Name) { zone = z }
+  }
+  return zone
+}
@@ -0,0 +1,35 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestRecordZone(t *testing.T) {
+  zones := []*route53.HostedZone{
+    {Id: aws.String("top"), Name: aws.String("example.com.")},
+    {Id: aws.String("craft"), Name: aws.String("craft.example.com.")},
+    {Id: aws.String("other"), Name: aws.String("ample.com.")},
+  }
+  testVals := [][]string {
+    { "proxy.craft.example.com.", "craft", },
+    { "world.proxy.craft.example.com", "craft", },
+    { "craft.example.com.", "craft", },
+    { "www.example.com.", "top", },
+    { "example.com", "top", },
+  }
+  for _, v := range testVals {
+    z := recordZone(v[0], zones)
+    if assert.NotNil(t, z, "Expecting a zone for %s", v[0]) {
+      assert.Equal(t, v[1], *z.Id, "Expecting %s to be in zone %s", v[0], v[1])
+    }
+  }
+  assert.Nil(t, recordZone("example.org.", zones))
+}
+
+func TestSameDNSName(t *testing.T) {
+  assert.True(t, sameDNSName("world.craft.example.com.", "World.craft.example.com"))
+  assert.False(t, sameDNSName("world.craft.example.com.", "world.example.com."))
+}
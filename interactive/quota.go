@@ -0,0 +1,175 @@
+package interactive
+
+import (
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Per-user quotas.
+// Limits on a user's concurrent servers and their total memory reservation, across
+// all clusters, and on the bytes they have archived. Server launch and start check
+// the first two, commands that take a snapshot check the last, before going to ECS or S3.
+// An admin can go over with --override.
+// The quota file has a Default and per user entries; a user's entry replaces the default,
+// and 0 means no limit:
+//   {"Default": {"MaxServers": 2, "MaxMemory": 4096, "MaxArchiveBytes": 5368709120},
+//    "Users": {"jdr": {"MaxServers": 5}}}
+//
+
+const quotaFile = "./.ecs-craft_quotas.json"
+
+type quota struct {
+  MaxServers int
+  MaxMemory int64         // MiB, task memory reservation.
+  MaxArchiveBytes int64
+}
+
+func (q quota) unlimited() bool { return q.MaxServers == 0 && q.MaxMemory == 0 && q.MaxArchiveBytes == 0 }
+
+type quotaConfig struct {
+  Default quota
+  Users map[string]quota
+}
+
+func loadQuotaConfig(fileName string) (qc quotaConfig, err error) {
+  qc.Users = make(map[string]quota)
+  data, err := ioutil.ReadFile(fileName)
+  if os.IsNotExist(err) { return qc, nil }
+  if err != nil { return qc, err }
+  if err = json.Unmarshal(data, &qc); err != nil { return qc, fmt.Errorf("Bad quota file %s: %s", fileName, err) }
+  if qc.Users == nil { qc.Users = make(map[string]quota) }
+  return qc, nil
+}
+
+func (qc quotaConfig) forUser(user string) quota {
+  if q, ok := qc.Users[user]; ok { return q }
+  return qc.Default
+}
+
+// What a user has, or is asking for.
+type quotaUsage struct {
+  Servers int
+  Memory int64
+  ArchiveBytes int64
+  Snapshots int     // New snapshots asked for, their size isn't known until they're taken.
+}
+
+// check says what, if anything, adding add to use would go over.
+func (q quota) check(user string, use, add quotaUsage) (error) {
+  if q.MaxServers > 0 && add.Servers > 0 && use.Servers + add.Servers > q.MaxServers {
+    return fmt.Errorf("%s already has %d of %d servers running. Terminate one first, or use --override.",
+      user, use.Servers, q.MaxServers)
+  }
+  if q.MaxMemory > 0 && add.Memory > 0 && use.Memory + add.Memory > q.MaxMemory {
+    return fmt.Errorf("%s's servers would reserve %d MiB, over the %d MiB quota (%d MiB in use). Use a smaller task definition, or --override.",
+      user, use.Memory + add.Memory, q.MaxMemory, use.Memory)
+  }
+  if q.MaxArchiveBytes > 0 && add.Snapshots > 0 && use.ArchiveBytes >= q.MaxArchiveBytes {
+    return fmt.Errorf("%s has %.2f GB archived, the quota is %.2f GB. Remove old snapshots first, or use --override.",
+      user, float64(use.ArchiveBytes) / bytesPerGB, float64(q.MaxArchiveBytes) / bytesPerGB)
+  }
+  return nil
+}
+
+// userUsage is what the user has running on every cluster, and in the bucket if withArchive.
+func userUsage(user, bucket string, withArchive bool, sess *session.Session) (use quotaUsage, err error) {
+  clusters, err := awslib.GetAllClusterDescriptions(sess)
+  if err != nil { return use, err }
+  for _, c := range clusters {
+    servers, err := getServers(*c.ClusterName, sess)
+    if err != nil { return use, fmt.Errorf("Failed to count %s's servers on %s: %s", user, *c.ClusterName, err) }
+    for _, s := range servers {
+      if s.User != user { continue }
+      use.Servers++
+      use.Memory += taskRequirements(s.DeepTask.TaskDefinition).Memory
+    }
+  }
+  if withArchive {
+    sizes, err := archiveSizes(bucket, user + "/", sess)
+    if err != nil { return use, fmt.Errorf("Failed to size %s's archives in %s: %s", user, bucket, err) }
+    for _, size := range sizes { use.ArchiveBytes += size }
+  }
+  return use, nil
+}
+
+// enforceQuota refuses add if it takes the user over quota, unless override.
+func enforceQuota(user, bucket string, add quotaUsage, override bool, sess *session.Session) (error) {
+  qc, err := loadQuotaConfig(quotaFile)
+  if err != nil { return err }
+  q := qc.forUser(user)
+  if q.unlimited() { return nil }
+  use, err := userUsage(user, bucket, add.Snapshots > 0 && q.MaxArchiveBytes > 0, sess)
+  if err != nil { return err }
+  err = q.check(user, use, add)
+  if err != nil && override {
+    fmt.Printf("%sOverriding quota: %s%s\n", warnColor, err, resetColor)
+    return nil
+  }
+  return err
+}
+
+// checkServerQuota is for launching another server for the user on the task definition.
+func checkServerQuota(user, tdArn string, override bool, sess *session.Session) (error) {
+  td, err := getTaskDefinition(tdArn, sess)
+  if err != nil { return err }
+  return enforceQuota(user, DefaultArchiveBucket, quotaUsage{Servers: 1, Memory: taskRequirements(td).Memory}, override, sess)
+}
+
+// checkReplaceQuota is for restarting the server onto the task definition. The server is
+// already counted, so only the memory the new task definition reserves over its current one is added.
+func checkReplaceQuota(s *mclib.Server, tdArn string, override bool, sess *session.Session) (error) {
+  if tdArn == "" { return nil }
+  td, err := getTaskDefinition(tdArn, sess)
+  if err != nil { return err }
+  more := taskRequirements(td).Memory - taskRequirements(s.DeepTask.TaskDefinition).Memory
+  if more <= 0 { return nil }
+  return enforceQuota(s.User, DefaultArchiveBucket, quotaUsage{Memory: more}, override, sess)
+}
+
+// checkSnapshotQuota is for taking a new snapshot of the server.
+func checkSnapshotQuota(s *mclib.Server, override bool, sess *session.Session) (error) {
+  bucket := s.ArchiveBucket
+  if bucket == "" { bucket = DefaultArchiveBucket }
+  return enforceQuota(s.User, bucket, quotaUsage{Snapshots: 1}, override, sess)
+}
+
+func doQuotaShowCmd(sess *session.Session) (error) {
+  qc, err := loadQuotaConfig(quotaFile)
+  if err != nil { return err }
+  q := qc.forUser(userNameArg)
+  use, err := userUsage(userNameArg, quotaBucketArg, true, sess)
+  if err != nil { return err }
+
+  source := "default"
+  if _, ok := qc.Users[userNameArg]; ok { source = "user" }
+  fmt.Printf("%s%s quotas for %s (%s)%s\n", titleColor, time.Now().Local().Format(time.RFC1123), userNameArg, source, resetColor)
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sQuota\tIn Use\tLimit\tLeft%s\n", titleColor, resetColor)
+  printQuotaLine(w, "Servers", float64(use.Servers), float64(q.MaxServers), "%.0f")
+  printQuotaLine(w, "Memory (MiB)", float64(use.Memory), float64(q.MaxMemory), "%.0f")
+  printQuotaLine(w, "Archive (GB)", float64(use.ArchiveBytes) / bytesPerGB, float64(q.MaxArchiveBytes) / bytesPerGB, "%.2f")
+  w.Flush()
+  return nil
+}
+
+func printQuotaLine(w *tabwriter.Writer, name string, use, limit float64, format string) {
+  if limit == 0 {
+    fmt.Fprintf(w, "%s%s\t" + format + "\tnone\t-%s\n", nullColor, name, use, resetColor)
+    return
+  }
+  color := successColor
+  if use >= limit { color = failColor }
+  fmt.Fprintf(w, "%s%s\t" + format + "\t" + format + "\t" + format + "%s\n", color, name, use, limit, limit - use, resetColor)
+}
@@ -0,0 +1,53 @@
+package interactive
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestLoadQuotaConfig(t *testing.T) {
+  dir, err := ioutil.TempDir("", "quotas")
+  assert.NoError(t, err)
+  defer os.RemoveAll(dir)
+  fileName := filepath.Join(dir, "quotas.json")
+
+  qc, err := loadQuotaConfig(fileName)
+  assert.NoError(t, err)
+  assert.True(t, qc.forUser("jdr").unlimited())
+
+  err = ioutil.WriteFile(fileName, []byte(`{"Default": {"MaxServers": 2, "MaxMemory": 4096},
+    "Users": {"jdr": {"MaxServers": 5}}}`), 0600)
+  assert.NoError(t, err)
+  qc, err = loadQuotaConfig(fileName)
+  assert.NoError(t, err)
+  assert.Equal(t, quota{MaxServers: 5}, qc.forUser("jdr"))
+  assert.Equal(t, quota{MaxServers: 2, MaxMemory: 4096}, qc.forUser("someone"))
+
+  assert.NoError(t, ioutil.WriteFile(fileName, []byte(`{"Default": `), 0600))
+  _, err = loadQuotaConfig(fileName)
+  assert.Error(t, err)
+}
+
+func TestQuotaCheck(t *testing.T) {
+  q := quota{MaxServers: 2, MaxMemory: 4096, MaxArchiveBytes: 1 << 30}
+  server := quotaUsage{Servers: 1, Memory: 2048}
+
+  assert.NoError(t, q.check("jdr", quotaUsage{}, server))
+  assert.NoError(t, q.check("jdr", quotaUsage{Servers: 1, Memory: 2048}, server))
+
+  err := q.check("jdr", quotaUsage{Servers: 2, Memory: 1024}, server)
+  if assert.Error(t, err) { assert.Contains(t, err.Error(), "2 of 2 servers") }
+  err = q.check("jdr", quotaUsage{Servers: 1, Memory: 3072}, server)
+  if assert.Error(t, err) { assert.Contains(t, err.Error(), "5120 MiB") }
+
+  // Archive size only matters for new snapshots.
+  full := quotaUsage{ArchiveBytes: 2 << 30}
+  assert.NoError(t, q.check("jdr", full, server))
+  err = q.check("jdr", full, quotaUsage{Snapshots: 1})
+  if assert.Error(t, err) { assert.Contains(t, err.Error(), "--override") }
+
+  assert.NoError(t, quota{}.check("jdr", quotaUsage{Servers: 100, ArchiveBytes: 1 << 40}, quotaUsage{Servers: 1, Snapshots: 1}))
+}
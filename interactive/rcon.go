@@ -0,0 +1,103 @@
+package interactive
+
+import (
+  "bytes"
+  "encoding/binary"
+  "fmt"
+  "io"
+  "net"
+  "time"
+)
+
+//
+// A minimal RCON client (the Source RCON protocol that minecraft speaks).
+// Just enough to log in and run a command.
+//
+
+const (
+  rconTypeResponse int32 = 0
+  rconTypeCommand int32 = 2
+  rconTypeLogin int32 = 3
+
+  rconMaxBody = 4096
+)
+
+type rconClient struct {
+  conn net.Conn
+  timeout time.Duration
+  nextID int32
+}
+
+// dialRcon connects to addr and authenticates with password.
+func dialRcon(addr, password string, timeout time.Duration) (rc *rconClient, err error) {
+  conn, err := net.DialTimeout("tcp", addr, timeout)
+  if err != nil { return rc, err }
+
+  rc = &rconClient{conn: conn, timeout: timeout, nextID: 1}
+  id, _, err := rc.exchange(rconTypeLogin, password)
+  if err != nil {
+    conn.Close()
+    return nil, err
+  }
+  if id == -1 {
+    conn.Close()
+    return nil, fmt.Errorf("rcon authentication failed for %s", addr)
+  }
+  return rc, nil
+}
+
+// Command sends cmd to the server and returns the server's response.
+func (rc *rconClient) Command(cmd string) (string, error) {
+  _, body, err := rc.exchange(rconTypeCommand, cmd)
+  return body, err
+}
+
+func (rc *rconClient) Close() error {
+  return rc.conn.Close()
+}
+
+func (rc *rconClient) exchange(packetType int32, body string) (id int32, response string, err error) {
+  if len(body) > rconMaxBody {
+    return id, response, fmt.Errorf("rcon request too long (%d bytes)", len(body))
+  }
+  rc.conn.SetDeadline(time.Now().Add(rc.timeout))
+
+  reqID := rc.nextID
+  rc.nextID++
+  if _, err = rc.conn.Write(encodeRconPacket(reqID, packetType, body)); err != nil {
+    return id, response, err
+  }
+
+  // Login replies are preceeded by an empty response packet on some servers.
+  for {
+    var t int32
+    id, t, response, err = readRconPacket(rc.conn)
+    if err != nil { return id, response, err }
+    if packetType == rconTypeLogin && t == rconTypeResponse { continue }
+    return id, response, err
+  }
+}
+
+func encodeRconPacket(id, packetType int32, body string) []byte {
+  buf := new(bytes.Buffer)
+  binary.Write(buf, binary.LittleEndian, int32(len(body)+10))
+  binary.Write(buf, binary.LittleEndian, id)
+  binary.Write(buf, binary.LittleEndian, packetType)
+  buf.WriteString(body)
+  buf.Write([]byte{0, 0})
+  return buf.Bytes()
+}
+
+func readRconPacket(r io.Reader) (id, packetType int32, body string, err error) {
+  var size int32
+  if err = binary.Read(r, binary.LittleEndian, &size); err != nil { return id, packetType, body, err }
+  if size < 10 || size > rconMaxBody+10 {
+    return id, packetType, body, fmt.Errorf("bad rcon packet size: %d", size)
+  }
+  p := make([]byte, size)
+  if _, err = io.ReadFull(r, p); err != nil { return id, packetType, body, err }
+  id = int32(binary.LittleEndian.Uint32(p[0:4]))
+  packetType = int32(binary.LittleEndian.Uint32(p[4:8]))
+  body = string(bytes.TrimRight(p[8:], "\x00"))
+  return id, packetType, body, err
+}
@@ -0,0 +1,334 @@
+package interactive
+
+import (
+  "bytes"
+  "encoding/binary"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Readiness.
+// ECS says a task is RUNNING as soon as the containers start,
+// but the JVM takes a good while longer to start accepting logins.
+// These probes let us wait for the server itself.
+//
+
+const (
+  defaultReadyTimeout = "5m"
+  readyPollInterval = 5 * time.Second
+  probeTimeout = 5 * time.Second
+  statusProbeTimeout = 2 * time.Second
+
+  // Minecraft listens here inside the container, bungee on 25577.
+  minecraftContainerPort = 25565
+  bungeeContainerPort = 25577
+)
+
+// A single check that a server is up, eg. can we connect, can we ping, can we log into rcon.
+type readinessProbe struct {
+  Name string
+  Check func(timeout time.Duration) (error)
+}
+
+func tcpProbe(addr string) readinessProbe {
+  return readinessProbe{
+    Name: "tcp",
+    Check: func(timeout time.Duration) (error) {
+      conn, err := net.DialTimeout("tcp", addr, timeout)
+      if err == nil { conn.Close() }
+      return err
+    },
+  }
+}
+
+func statusPingProbe(addr string) readinessProbe {
+  return readinessProbe{
+    Name: "ping",
+    Check: func(timeout time.Duration) (error) {
+      _, err := statusPing(addr, timeout)
+      return err
+    },
+  }
+}
+
+func rconListProbe(addr, password string) readinessProbe {
+  return readinessProbe{
+    Name: "rcon",
+    Check: func(timeout time.Duration) (error) {
+      rc, err := dialRcon(addr, password, timeout)
+      if err != nil { return err }
+      defer rc.Close()
+      _, err = rc.Command("list")
+      return err
+    },
+  }
+}
+
+// serverProbes returns the probes that must all pass before we consider
+// a minecraft server ready for players.
+func serverProbes(s *mclib.Server) (probes []readinessProbe) {
+  addr := net.JoinHostPort(s.PublicServerIp, s.ServerPort)
+  probes = append(probes, tcpProbe(addr), statusPingProbe(addr))
+  if password, ok := serverRconPassword(s); ok && s.RconPort != "" {
+    probes = append(probes, rconListProbe(net.JoinHostPort(s.PublicServerIp, s.RconPort), password))
+  }
+  return probes
+}
+
+// proxyProbes check that bungee is accepting connections on the
+// host port mapped to the proxy container.
+func proxyProbes(p *mclib.Proxy, task *ecs.Task, td *ecs.TaskDefinition) (probes []readinessProbe, err error) {
+  port, ok := hostPort(task, td, mclib.BungeeProxyServerContainerName, bungeeContainerPort)
+  if !ok {
+    port, ok = hostPort(task, td, mclib.BungeeProxyServerContainerName, minecraftContainerPort)
+  }
+  if !ok { return probes, fmt.Errorf("Can't find a host port for proxy %s.", p.Name) }
+  addr := net.JoinHostPort(p.PublicIpAddress(), strconv.FormatInt(port, 10))
+  probes = append(probes, tcpProbe(addr), statusPingProbe(addr))
+  return probes, err
+}
+
+// serverRconPassword is the password the server was given, or if its task only has
+// the reference, what the secret store has for it.
+func serverRconPassword(s *mclib.Server) (string, bool) {
+  env, ok := s.ServerEnvironment()
+  if !ok { return "", false }
+  if pw := env[mclib.RconPasswordKey]; pw != "" { return pw, true }
+  if ref, ok := env[mclib.RconPasswordKey + secretRefSuffix]; ok {
+    pw, err := resolveSecret(ref, s.AWSSession)
+    if err == nil { return pw, true }
+    log.Debug(fmt.Sprintf("Failed to resolve RCON password for %s: %s", s.Name, err))
+  }
+  return "", false
+}
+
+// dialServerRcon connects to a server's RCON port with its password.
+func dialServerRcon(s *mclib.Server) (*rconClient, error) {
+  password, ok := serverRconPassword(s)
+  if !ok || s.RconPort == "" { return nil, fmt.Errorf("No RCON for server %s.", s.Name) }
+  return dialRcon(net.JoinHostPort(s.PublicServerIp, s.RconPort), password, probeTimeout)
+}
+
+// serverRconCommand runs one command on a server.
+func serverRconCommand(s *mclib.Server, cmd string) (string, error) {
+  rc, err := dialServerRcon(s)
+  if err != nil { return "", err }
+  defer rc.Close()
+  return rc.Command(cmd)
+}
+
+// hostPort finds the host side of a container port binding.
+// awsvpc tasks have no bindings, the container port is on the task's own ENI
+// if the task definition maps it.
+func hostPort(task *ecs.Task, td *ecs.TaskDefinition, containerName string, containerPort int64) (int64, bool) {
+  if task == nil { return 0, false }
+  for _, c := range task.Containers {
+    if c.Name == nil || *c.Name != containerName { continue }
+    for _, b := range c.NetworkBindings {
+      if b.ContainerPort != nil && *b.ContainerPort == containerPort && b.HostPort != nil {
+        return *b.HostPort, true
+      }
+    }
+    if eniID, _, _ := taskENI(task); eniID != "" && len(c.NetworkBindings) == 0 {
+      return containerPort, mapsPort(td, containerName, containerPort)
+    }
+  }
+  return 0, false
+}
+
+// mapsPort is true if the task definition's container has a port mapping for containerPort.
+func mapsPort(td *ecs.TaskDefinition, containerName string, containerPort int64) (bool) {
+  if td == nil { return false }
+  for _, cd := range td.ContainerDefinitions {
+    if cd.Name == nil || *cd.Name != containerName { continue }
+    for _, pm := range cd.PortMappings {
+      if pm.ContainerPort != nil && *pm.ContainerPort == containerPort { return true }
+    }
+  }
+  return false
+}
+
+func describeTask(clusterName, taskArn string, sess *session.Session) (*ecs.Task, error) {
+  ecsSvc := ecs.New(sess)
+  resp, err := ecsSvc.DescribeTasks(&ecs.DescribeTasksInput{
+    Cluster: aws.String(clusterName),
+    Tasks: []*string{aws.String(taskArn)},
+  })
+  if err != nil { return nil, err }
+  if len(resp.Tasks) == 0 {
+    if len(resp.Failures) > 0 {
+      return nil, fmt.Errorf("Failed to describe task %s: %s", taskArn, *resp.Failures[0].Reason)
+    }
+    return nil, fmt.Errorf("No task found for %s.", taskArn)
+  }
+  return resp.Tasks[0], nil
+}
+
+// checkReady runs each of the probes once.
+// Returns the name of the first probe to fail.
+func checkReady(probes []readinessProbe, timeout time.Duration) (failed string, err error) {
+  for _, p := range probes {
+    if err = p.Check(timeout); err != nil {
+      return p.Name, err
+    }
+  }
+  return failed, nil
+}
+
+// waitForReady polls the probes until they all pass, or timeout.
+func waitForReady(probes []readinessProbe, timeout time.Duration) (error) {
+  deadline := time.Now().Add(timeout)
+  for {
+    failed, err := checkReady(probes, probeTimeout)
+    if err == nil { return nil }
+    if time.Now().After(deadline) {
+      return fmt.Errorf("not ready after %s (%s probe: %s)", timeout, failed, err)
+    }
+    time.Sleep(readyPollInterval)
+  }
+}
+
+// onReady returns immediately and calls back when the probes pass or we give up.
+// Modeled on awslib.OnTaskRunning.
+func onReady(probes []readinessProbe, timeout time.Duration, do func(time.Duration, error)) {
+  go func() {
+    start := time.Now()
+    err := waitForReady(probes, timeout)
+    do(time.Since(start), err)
+  }()
+}
+
+// readyStatus does one quick pass on a list of servers
+// concurrently and returns a display string for each.
+func readyStatus(servers []*mclib.Server) (map[*mclib.Server]string) {
+  status := make(map[*mclib.Server]string)
+  var mu sync.Mutex
+  var wg sync.WaitGroup
+  for _, s := range servers {
+    wg.Add(1)
+    go func(s *mclib.Server) {
+      defer wg.Done()
+      r := "yes"
+      if failed, err := checkReady(serverProbes(s), statusProbeTimeout); err != nil {
+        r = fmt.Sprintf("no (%s)", failed)
+      }
+      mu.Lock()
+      status[s] = r
+      mu.Unlock()
+    }(s)
+  }
+  wg.Wait()
+  return status
+}
+
+//
+// Server List Ping.
+// http://wiki.vg/Server_List_Ping
+//
+
+type serverStatus struct {
+  Version struct {
+    Name string `json:"name"`
+    Protocol int `json:"protocol"`
+  } `json:"version"`
+  Players struct {
+    Max int `json:"max"`
+    Online int `json:"online"`
+  } `json:"players"`
+  Description json.RawMessage `json:"description"`
+}
+
+func statusPing(addr string, timeout time.Duration) (status *serverStatus, err error) {
+  host, portString, err := net.SplitHostPort(addr)
+  if err != nil { return status, err }
+  port, err := strconv.ParseUint(portString, 10, 16)
+  if err != nil { return status, err }
+
+  conn, err := net.DialTimeout("tcp", addr, timeout)
+  if err != nil { return status, err }
+  defer conn.Close()
+  conn.SetDeadline(time.Now().Add(timeout))
+
+  // Handshake, next state: status.
+  hs := new(bytes.Buffer)
+  writeVarInt(hs, 0x00)
+  writeVarInt(hs, -1)
+  writeVarInt(hs, int32(len(host)))
+  hs.WriteString(host)
+  binary.Write(hs, binary.BigEndian, uint16(port))
+  writeVarInt(hs, 1)
+  if err = writePacket(conn, hs.Bytes()); err != nil { return status, err }
+
+  // Status request.
+  if err = writePacket(conn, []byte{0x00}); err != nil { return status, err }
+
+  // Response: length, packet id, json string.
+  if _, err = readVarInt(conn); err != nil { return status, err }
+  id, err := readVarInt(conn)
+  if err != nil { return status, err }
+  if id != 0x00 { return status, fmt.Errorf("unexpected status packet id: %d", id) }
+  n, err := readVarInt(conn)
+  if err != nil { return status, err }
+  if n < 0 || n > 1<<20 { return status, fmt.Errorf("bad status response length: %d", n) }
+  js := make([]byte, n)
+  if _, err = io.ReadFull(conn, js); err != nil { return status, err }
+
+  status = new(serverStatus)
+  err = json.Unmarshal(js, status)
+  return status, err
+}
+
+// MOTD as plain text, description is either a string or a chat object.
+func (ss *serverStatus) MOTD() string {
+  var s string
+  if err := json.Unmarshal(ss.Description, &s); err == nil { return s }
+  var chat struct { Text string `json:"text"` }
+  json.Unmarshal(ss.Description, &chat)
+  return strings.TrimSpace(chat.Text)
+}
+
+func writePacket(w io.Writer, data []byte) (error) {
+  p := new(bytes.Buffer)
+  writeVarInt(p, int32(len(data)))
+  p.Write(data)
+  _, err := w.Write(p.Bytes())
+  return err
+}
+
+func writeVarInt(w io.ByteWriter, v int32) {
+  uv := uint32(v)
+  for {
+    if uv & ^uint32(0x7F) == 0 {
+      w.WriteByte(byte(uv))
+      return
+    }
+    w.WriteByte(byte(uv&0x7F | 0x80))
+    uv >>= 7
+  }
+}
+
+func readVarInt(r io.Reader) (int32, error) {
+  var result uint32
+  b := make([]byte, 1)
+  for i := uint(0); i < 5; i++ {
+    if _, err := io.ReadFull(r, b); err != nil { return 0, err }
+    result |= uint32(b[0]&0x7F) << (7 * i)
+    if b[0]&0x80 == 0 {
+      return int32(result), nil
+    }
+  }
+  return 0, fmt.Errorf("varint too long")
+}
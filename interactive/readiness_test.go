@@ -0,0 +1,69 @@
+package interactive
+
+import (
+  "bytes"
+  "encoding/json"
+  "net"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestVarIntRoundTrip(t *testing.T) {
+  for _, v := range []int32{0, 1, 127, 128, 255, 25565, 2097151, 2147483647, -1} {
+    b := new(bytes.Buffer)
+    writeVarInt(b, v)
+    got, err := readVarInt(b)
+    assert.NoError(t, err)
+    assert.Equal(t, v, got, "Expecting varint %d to round trip.", v)
+  }
+}
+
+func TestRconPacketRoundTrip(t *testing.T) {
+  p := encodeRconPacket(7, rconTypeCommand, "list")
+  id, pt, body, err := readRconPacket(bytes.NewReader(p))
+  assert.NoError(t, err)
+  assert.Equal(t, int32(7), id)
+  assert.Equal(t, rconTypeCommand, pt)
+  assert.Equal(t, "list", body)
+}
+
+func TestStatusPing(t *testing.T) {
+  l, err := net.Listen("tcp", "127.0.0.1:0")
+  if !assert.NoError(t, err) { return }
+  defer l.Close()
+
+  go func() {
+    conn, err := l.Accept()
+    if err != nil { return }
+    defer conn.Close()
+    // handshake and status request.
+    for i := 0; i < 2; i++ {
+      n, _ := readVarInt(conn)
+      buf := make([]byte, n)
+      conn.Read(buf)
+    }
+    js, _ := json.Marshal(map[string]interface{}{
+      "version": map[string]interface{}{"name": "1.10.2", "protocol": 210},
+      "players": map[string]interface{}{"max": 20, "online": 3},
+      "description": map[string]interface{}{"text": "A test server"},
+    })
+    p := new(bytes.Buffer)
+    p.WriteByte(0x00)
+    writeVarInt(p, int32(len(js)))
+    p.Write(js)
+    writePacket(conn, p.Bytes())
+  }()
+
+  status, err := statusPing(l.Addr().String(), time.Second)
+  if !assert.NoError(t, err) { return }
+  assert.Equal(t, 3, status.Players.Online)
+  assert.Equal(t, 20, status.Players.Max)
+  assert.Equal(t, "A test server", status.MOTD())
+}
+
+func TestWaitForReadyTimesOut(t *testing.T) {
+  failing := readinessProbe{Name: "never", Check: func(time.Duration) error { return assert.AnError }}
+  err := waitForReady([]readinessProbe{failing}, 0)
+  assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package interactive
+
+import (
+  "crypto/aes"
+  "crypto/cipher"
+  "crypto/rand"
+  "encoding/base64"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "regexp"
+  "strings"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/awserr"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ssm"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Secrets.
+// RCON passwords are generated per server, and for each proxy's hub, and kept in
+// a secret store: SSM Parameter Store, or an encrypted local file for development.
+// Pick with ECS_CRAFT_SECRETS=ssm|file, ssm is the default.
+//
+// RunTask overrides can only add or replace environment variables, and the
+// containers don't read secret stores, so the task gets the password itself in
+// KEY, alongside where it's kept in KEY_SECRET=ssm:/ecs-craft/.... Both show up in
+// DescribeTasks, our own output redacts the password. The store is what keeps
+// the password the same across restarts.
+// The proxy's bungee keeps mclib's default password: mclib's proxy calls dial
+// its RCON with that and can't be given another.
+//
+
+const (
+  secretsBackendEnv = "ECS_CRAFT_SECRETS"
+  secretKeyEnv = "ECS_CRAFT_SECRET_KEY" // hex encoded 32 byte AES key for the file store.
+  secretsFile = "./.ecs-craft_secrets"
+  secretKeyFile = "./.ecs-craft_secret_key"
+
+  ssmSecretScheme = "ssm"
+  fileSecretScheme = "file"
+
+  secretRefSuffix = "_SECRET"
+  redactedValue = "<redacted>"
+  rconPasswordBytes = 18
+
+  serverSecretKind = "server"
+)
+
+type secretStore interface {
+  Scheme() string
+  Get(name string) (value string, found bool, err error)
+  Put(name, value string) (error)
+}
+
+func newSecretStore(sess *session.Session) (secretStore, error) {
+  return secretStoreFor(os.Getenv(secretsBackendEnv), sess)
+}
+
+func secretStoreFor(scheme string, sess *session.Session) (secretStore, error) {
+  switch scheme {
+  case "", ssmSecretScheme: return ssmSecretStore{svc: ssm.New(sess)}, nil
+  case fileSecretScheme: return newFileSecretStore(secretsFile, secretKeyFile)
+  }
+  return nil, fmt.Errorf("Unknown secret store %q, expecting %s or %s.", scheme, ssmSecretScheme, fileSecretScheme)
+}
+
+// A reference is scheme:name.
+func secretRef(store secretStore, name string) string {
+  return store.Scheme() + ":" + name
+}
+
+func isSecretRef(v string) bool {
+  return strings.HasPrefix(v, ssmSecretScheme + ":/") || strings.HasPrefix(v, fileSecretScheme + ":/")
+}
+
+// resolveSecret gets the value a reference points at.
+func resolveSecret(ref string, sess *session.Session) (string, error) {
+  i := strings.Index(ref, ":")
+  if i < 0 { return "", fmt.Errorf("Bad secret reference %q.", ref) }
+  store, err := secretStoreFor(ref[:i], sess)
+  if err != nil { return "", err }
+  v, found, err := store.Get(ref[i+1:])
+  if err == nil && !found { err = fmt.Errorf("No secret %s.", ref) }
+  return v, err
+}
+
+// Server names are only unique per user.
+func rconSecretName(clusterName, kind, userName, name string) string {
+  return fmt.Sprintf("/ecs-craft/%s/%s/%s/%s/rcon-password", clusterName, kind, userName, name)
+}
+
+// Where the password was kept before the user was in the name.
+func legacyRconSecretName(clusterName, kind, name string) string {
+  return fmt.Sprintf("/ecs-craft/%s/%s/%s/rcon-password", clusterName, kind, name)
+}
+
+// setRconSecret puts the RCON password for kind/user/name, and a reference to where it's kept,
+// into env. The password is made the first time we see the name, or carried over from where
+// it was kept before, so it stays the same across restarts.
+func setRconSecret(env map[string]string, clusterName, kind, userName, name string, sess *session.Session) (error) {
+  store, err := newSecretStore(sess)
+  if err != nil { return err }
+  secretName := rconSecretName(clusterName, kind, userName, name)
+  password, found, err := store.Get(secretName)
+  if err != nil { return fmt.Errorf("Failed to get RCON password for %s: %s", name, err) }
+  if !found {
+    password, found, err = store.Get(legacyRconSecretName(clusterName, kind, name))
+    if err != nil { return fmt.Errorf("Failed to get RCON password for %s: %s", name, err) }
+    if !found {
+      if password, err = randomPassword(); err != nil { return err }
+    }
+    if err = store.Put(secretName, password); err != nil {
+      return fmt.Errorf("Failed to save RCON password for %s: %s", name, err)
+    }
+  }
+  env[mclib.RconPasswordKey] = password
+  env[mclib.RconPasswordKey + secretRefSuffix] = secretRef(store, secretName)
+  return nil
+}
+
+func randomPassword() (string, error) {
+  b := make([]byte, rconPasswordBytes)
+  if _, err := rand.Read(b); err != nil { return "", err }
+  return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+//
+// Redaction
+//
+
+var secretKeyPattern = regexp.MustCompile(`(?i)PASSWORD|SECRET|TOKEN|CREDENTIAL`)
+
+// redactValue hides the value of anything that looks like a secret.
+// References are fine to show.
+func redactValue(key, value string) string {
+  if value == "" || isSecretRef(value) || !secretKeyPattern.MatchString(key) { return value }
+  return redactedValue
+}
+
+//
+// SSM Parameter Store
+//
+
+type ssmSecretStore struct {
+  svc *ssm.SSM
+}
+
+func (s ssmSecretStore) Scheme() string { return ssmSecretScheme }
+
+func (s ssmSecretStore) Get(name string) (string, bool, error) {
+  resp, err := s.svc.GetParameter(&ssm.GetParameterInput{
+    Name: aws.String(name),
+    WithDecryption: aws.Bool(true),
+  })
+  if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound { return "", false, nil }
+  if err != nil { return "", false, err }
+  return aws.StringValue(resp.Parameter.Value), true, nil
+}
+
+func (s ssmSecretStore) Put(name, value string) (error) {
+  _, err := s.svc.PutParameter(&ssm.PutParameterInput{
+    Name: aws.String(name),
+    Value: aws.String(value),
+    Type: aws.String(ssm.ParameterTypeSecureString),
+    Overwrite: aws.Bool(true),
+  })
+  return err
+}
+
+//
+// Local encrypted file, AES-GCM over a JSON map.
+//
+
+type fileSecretStore struct {
+  File string
+  aead cipher.AEAD
+}
+
+// newFileSecretStore uses the key from ECS_CRAFT_SECRET_KEY, or from keyFile,
+// which is created if need be.
+func newFileSecretStore(fileName, keyFile string) (fs *fileSecretStore, err error) {
+  keyHex := os.Getenv(secretKeyEnv)
+  if keyHex == "" {
+    data, err := ioutil.ReadFile(keyFile)
+    switch {
+    case os.IsNotExist(err):
+      key := make([]byte, 32)
+      if _, err = rand.Read(key); err != nil { return fs, err }
+      keyHex = hex.EncodeToString(key)
+      if err = ioutil.WriteFile(keyFile, []byte(keyHex), 0600); err != nil { return fs, err }
+    case err != nil:
+      return fs, err
+    default:
+      keyHex = strings.TrimSpace(string(data))
+    }
+  }
+  key, err := hex.DecodeString(keyHex)
+  if err != nil { return fs, fmt.Errorf("Bad secret key: %s", err) }
+  block, err := aes.NewCipher(key)
+  if err != nil { return fs, err }
+  aead, err := cipher.NewGCM(block)
+  if err != nil { return fs, err }
+  return &fileSecretStore{File: fileName, aead: aead}, nil
+}
+
+func (fs *fileSecretStore) Scheme() string { return fileSecretScheme }
+
+func (fs *fileSecretStore) Get(name string) (string, bool, error) {
+  secrets, err := fs.load()
+  if err != nil { return "", false, err }
+  v, ok := secrets[name]
+  return v, ok, nil
+}
+
+func (fs *fileSecretStore) Put(name, value string) (error) {
+  secrets, err := fs.load()
+  if err != nil { return err }
+  secrets[name] = value
+  return fs.save(secrets)
+}
+
+func (fs *fileSecretStore) load() (secrets map[string]string, err error) {
+  secrets = make(map[string]string)
+  data, err := ioutil.ReadFile(fs.File)
+  if os.IsNotExist(err) { return secrets, nil }
+  if err != nil { return secrets, err }
+  n := fs.aead.NonceSize()
+  if len(data) < n { return secrets, fmt.Errorf("%s is too short to be a secrets file.", fs.File) }
+  plain, err := fs.aead.Open(nil, data[:n], data[n:], nil)
+  if err != nil { return secrets, fmt.Errorf("Can't decrypt %s, wrong key?", fs.File) }
+  err = json.Unmarshal(plain, &secrets)
+  return secrets, err
+}
+
+func (fs *fileSecretStore) save(secrets map[string]string) (error) {
+  plain, err := json.Marshal(secrets)
+  if err != nil { return err }
+  nonce := make([]byte, fs.aead.NonceSize())
+  if _, err = rand.Read(nonce); err != nil { return err }
+  return ioutil.WriteFile(fs.File, fs.aead.Seal(nonce, nonce, plain, nil), 0600)
+}
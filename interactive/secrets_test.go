@@ -0,0 +1,67 @@
+package interactive
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestFileSecretStore(t *testing.T) {
+  dir, err := ioutil.TempDir("", "secrets")
+  assert.NoError(t, err)
+  defer os.RemoveAll(dir)
+  fileName := filepath.Join(dir, "secrets")
+  keyFile := filepath.Join(dir, "key")
+
+  fs, err := newFileSecretStore(fileName, keyFile)
+  assert.NoError(t, err)
+  _, found, err := fs.Get("/ecs-craft/minecraft/server/world/rcon-password")
+  assert.NoError(t, err)
+  assert.False(t, found)
+
+  assert.NoError(t, fs.Put("/ecs-craft/minecraft/server/world/rcon-password", "sekrit"))
+  data, err := ioutil.ReadFile(fileName)
+  assert.NoError(t, err)
+  assert.NotContains(t, string(data), "sekrit")
+
+  // The key was saved, so a new store reads what the old one wrote.
+  fs, err = newFileSecretStore(fileName, keyFile)
+  assert.NoError(t, err)
+  v, found, err := fs.Get("/ecs-craft/minecraft/server/world/rcon-password")
+  assert.NoError(t, err)
+  assert.True(t, found)
+  assert.Equal(t, "sekrit", v)
+  assert.Equal(t, "file:/ecs-craft/minecraft/server/world/rcon-password",
+    secretRef(fs, "/ecs-craft/minecraft/server/world/rcon-password"))
+
+  // But not with a different key.
+  fs, err = newFileSecretStore(fileName, filepath.Join(dir, "other-key"))
+  assert.NoError(t, err)
+  _, _, err = fs.Get("/ecs-craft/minecraft/server/world/rcon-password")
+  assert.Error(t, err)
+}
+
+func TestRedactValue(t *testing.T) {
+  testVals := [][]string {
+    { "RCON_PASSWORD", "sekrit", redactedValue },
+    { "RCON_PASSWORD_SECRET", "ssm:/ecs-craft/minecraft/server/world/rcon-password", "ssm:/ecs-craft/minecraft/server/world/rcon-password" },
+    { "AWS_SECRET_ACCESS_KEY", "abc", redactedValue },
+    { "github_token", "abc", redactedValue },
+    { "MOTD", "Hello", "Hello" },
+    { "RCON_PASSWORD", "", "" },
+  }
+  for _, v := range testVals {
+    assert.Equal(t, v[2], redactValue(v[0], v[1]), "Expecting %s=%s to show as %s", v[0], v[1], v[2])
+  }
+}
+
+func TestRandomPassword(t *testing.T) {
+  a, err := randomPassword()
+  assert.NoError(t, err)
+  b, err := randomPassword()
+  assert.NoError(t, err)
+  assert.Len(t, a, 24)
+  assert.NotEqual(t, a, b)
+}
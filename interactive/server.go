@@ -47,10 +47,24 @@ func doLaunchServerCmd(sess *session.Session) (error) {
   tdArn := serverTaskArg
   cluster := currentCluster
 
+  settings, err := parseSettings(serverSetArg, serverPropsArg)
+  if err != nil { return err }
+  pl, err := placementFromArgs()
+  if err != nil { return err }
+
+  // A replacement for an existing server starts from its latest snapshot.
+  replaced, err := checkDuplicateServer(userName, serverName, "", tdArn, cluster, settings, pl, sess)
+  if replaced || err != nil { return err }
+  if err = checkServerQuota(userName, tdArn, quotaOverrideFlag, sess); err != nil { return err }
+
   ss, err := mclib.NewServerSpec(userName, serverName, region, bucketName, cluster, tdArn, sess)
   if err != nil { return err }
+  applySettings(ss.ServerContainerEnv(), settings)
+  applySettings(ss.ServerContainerEnv(), playerSettings(cluster, userName, serverName))
+  err = setRconSecret(ss.ServerContainerEnv(), cluster, serverSecretKind, userName, serverName, sess)
+  if err != nil { return err }
 
-  s, err := launchServer(ss, sess)
+  s, err := launchServer(ss, pl, sess)
   if err == nil {
     displayServer(s)
   }
@@ -67,8 +81,17 @@ func doStartServerCmd(sess *session.Session) (err error) {
   tdArn := serverTaskArg
   cluster := currentCluster
 
+  settings, err := parseSettings(serverSetArg, serverPropsArg)
+  if err != nil { return err }
+  pl, err := placementFromArgs()
+  if err != nil { return err }
+
+  replaced, err := checkDuplicateServer(userName, serverName, snapshotName, tdArn, cluster, settings, pl, sess)
+  if replaced || err != nil { return err }
+  if err = checkServerQuota(userName, tdArn, quotaOverrideFlag, sess); err != nil { return err }
+
   // startServer calls launchServer, which handles reporting on multiple tasks.
-  s, err := startServer(userName, serverName, region, bucketName, snapshotName, tdArn, cluster,sess)
+  s, err := startServer(userName, serverName, region, bucketName, snapshotName, tdArn, cluster, settings, pl, sess)
   if err == nil {
     displayServer(s)
   }
@@ -92,11 +115,11 @@ func displayServer(s *mclib.Server) {
   if debug {
     fmt.Printf("Server Environment:")
     for k, v := range serverEnv {
-      fmt.Printf("[%s] = %s", k, v)
+      fmt.Printf("[%s] = %s", k, redactValue(k, v))
     }
     fmt.Printf("\nController Environment:\n")
     for k, v := range contEnv {
-      fmt.Printf("[%s] = %s", k, v)
+      fmt.Printf("[%s] = %s", k, redactValue(k, v))
     }
     fmt.Printf("\n")
   }
@@ -114,7 +137,7 @@ func doRestartServerCmd(sess *session.Session) (err error) {
   backup := snapshotNameArg
 
   // Get set up .... find serer and proxies ....
-  oServer, err  := mclib.GetServerFromName(serverName, cluster, sess)
+  oServer, err  := getServer(serverName, cluster, sess)
   if err != nil { return fmt.Errorf("Failed to get current server, server not restarted: %s", err) }
 
   p, err := mclib.GetProxyFromName(proxyName, cluster, sess)
@@ -123,33 +146,134 @@ func doRestartServerCmd(sess *session.Session) (err error) {
   // TODO: revist if we want to start a new server even if this is not proxied.
   proxyFound, err := p.IsServerProxied(oServer) 
   if !proxyFound || err != nil {
-    if !proxyFound { err = fmt.Errorf("Server (%s) not proxied by (%d). Server not restarted: %s", oServer.Name, p.Name) }
+    if !proxyFound { err = fmt.Errorf("Server (%s) not proxied by (%s). Server not restarted.", oServer.Name, p.Name) }
     return fmt.Errorf("Failed to find proxy for server: %s", err)
   }
+  if err = checkReplaceQuota(oServer, tdArn, quotaOverrideFlag, sess); err != nil { return err }
+
+  nServer, err := restartServer(restartSpec{
+    Server: oServer,
+    Proxy: p,
+    Snapshot: backup,
+    TaskDef: tdArn,
+    Cluster: cluster,
+    ReadyTimeout: readyTimeoutArg,
+    KeepFailed: keepFailedFlag,
+  }, sess)
+  if err != nil { return err }
+
+  // ... and report success.
+  fmt.Printf("%sServer Restarted.%s\n", successColor, resetColor)
+  serverEnv, ok  := nServer.ServerEnvironment()
+  if !ok { fmt.Printf("Failed to get the server Environment.") }
+  controllerEnv, ok := nServer.ControllerEnvironment()
+  if !ok { fmt.Printf("Failed to get the controller Environment.") }
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 8, ' ', 0)
+  fmt.Fprintf(w, "%sCluster\tUser\tName\tTask\tRegion\tBucket\tWorld%s\n", titleColor, resetColor)
+  fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
+    cluster, serverEnv[mclib.ServerUserKey], serverEnv[mclib.ServerNameKey], tdArn, 
+    controllerEnv[mclib.ArchiveRegionKey], controllerEnv[mclib.ArchiveBucketKey], serverEnv[mclib.WorldKey],
+    resetColor)
+  w.Flush()
+
+  return err
+}
+
+// Everything needed to move a server onto a new task.
+type restartSpec struct {
+  Server *mclib.Server      // The server being replaced, it may have already stopped.
+  Proxy *mclib.Proxy        // nil if the server isn't proxied.
+  Snapshot string           // Empty to use the server's latest snapshot.
+  Env map[string]string     // Settings to change, the rest are carried over from Server.
+  TaskDef string
+  Cluster string
+  ReadyTimeout time.Duration
+  Placement placement       // Without a launch type, Fargate servers stay on Fargate.
+  KeepFailed bool           // Leave a new server that doesn't become ready running, to look at.
+}
+
+// restartServer starts a new server from a snapshot, waits for it to be ready, 
+// swaps proxy access, forced host and DNS over to it and then stops the old task.
+// If the new server came up, it's returned, even if there was a subsequent error.
+// A new server that doesn't become ready is stopped, unless rs.KeepFailed, leaving the old one as it was.
+func restartServer(rs restartSpec, sess *session.Session) (nServer *mclib.Server, err error) {
+  oServer := rs.Server
+  p := rs.Proxy
+  cluster := rs.Cluster
+  backup := rs.Snapshot
 
   // .... Get latest backup if one hasn't been specified.
   if backup == "" {
     // bu, err  := oServer.GetLatestWorldSnapshot()
     bu, err := oServer.LatestServerSnapshot()
-    if err != nil { return fmt.Errorf("Failed to get snapshot to start the server. Server not restarted: %s", err) }
+    if err != nil { return nServer, fmt.Errorf("Failed to get snapshot to start the server. Server not restarted: %s", err) }
     backup = bu.URI()
   }
 
   // .... start new server from backup ....
-  s, err := startServer(oServer.User, oServer.Name, *oServer.AWSSession.Config.Region, oServer.ArchiveBucket, backup, tdArn, cluster, sess)
+  settings := mergeSettings(carriedSettings(oServer), rs.Env)
+  pl := rs.Placement
+  if pl.LaunchType == "" {
+    if carried := carriedPlacement(oServer.DeepTask.Task, sess); carried.fargate() { pl = carried }
+  }
+  s, err := startServer(oServer.User, oServer.Name, *oServer.AWSSession.Config.Region, oServer.ArchiveBucket, 
+    backup, rs.TaskDef, cluster, settings, pl, sess)
   if err != nil {
-    fmt.Printf("%sError starting server, new server in unknown state. Server not restarted: %#v", err)
-    return err
+    return nServer, fmt.Errorf("Error starting server, new server in unknown state. Server not restarted: %s", err)
   }
   fmt.Printf("%sStarting new minecraft server with snapshot %s:%s\n", successColor, backup, resetColor)
 
   fmt.Printf("%sWaiting for new server to become available.%s\n", warnColor, resetColor)
-  nServer, err := mclib.GetServerWait(cluster, *s.TaskArn, sess)
+  nServer, err = mclib.GetServerWait(cluster, *s.TaskArn, sess)
   if err != nil {
-    return fmt.Errorf("Failed on waiting for new server to come up: %s. Server not restarted.", err)
+    err = fmt.Errorf("Failed on waiting for new server to come up: %s. Server not restarted.", err)
+    return s, stopFailedServer(s, cluster, rs.KeepFailed, err, sess)
+  }
+  fillServerAddress(nServer, sess)
+  fmt.Printf("%sNew server running, waiting for it to accept logins.%s\n", warnColor, resetColor)
+  err = waitForReady(serverProbes(nServer), rs.ReadyTimeout)
+  if err != nil {
+    err = fmt.Errorf("New server is %s. Server not restarted.", err)
+    return nServer, stopFailedServer(nServer, cluster, rs.KeepFailed, err, sess)
   }
   fmt.Printf("%sNew serrver up.%s\n", successColor, resetColor)
 
+  if p != nil {
+    proxies, _, err := mclib.GetProxies(cluster, sess)
+    if err != nil { return nServer, fmt.Errorf("Failed to get proxies, old server not stopped: %s", err) }
+    if shared := serverProxies(oServer, proxies); len(shared) > 1 {
+      err = swapSharedServer(shared, oServer, nServer)
+    } else {
+      err = swapProxiedServer(p, oServer, nServer, sess)
+    }
+    if err != nil { return nServer, err }
+  }
+
+  // ... Kill old server task .....
+  expectStop(*oServer.TaskArn)
+  _, err = awslib.StopTask(cluster, *oServer.TaskArn, sess)
+  if err != nil {
+    return nServer, fmt.Errorf("Failed to stop original server task. Everything else seemed to work: %s", err)
+  }
+  fmt.Printf("%sOld server sucesfullly terminated.%s\n", successColor, resetColor)
+
+  return nServer, err
+}
+
+// stopFailedServer stops a new server that didn't come up, unless keep, adding to err.
+func stopFailedServer(s *mclib.Server, clusterName string, keep bool, err error, sess *session.Session) (error) {
+  if keep { return fmt.Errorf("%s Left the new server (%s) running, both servers are running.", err, awslib.ShortArnString(s.TaskArn)) }
+  expectStop(*s.TaskArn)
+  if _, serr := awslib.StopTask(clusterName, *s.TaskArn, sess); serr != nil {
+    return fmt.Errorf("%s Failed to stop the new server (%s), both servers are running: %s", err, awslib.ShortArnString(s.TaskArn), serr)
+  }
+  return fmt.Errorf("%s Stopped the new server (%s).", err, awslib.ShortArnString(s.TaskArn))
+}
+
+// swapProxiedServer moves DNS, proxy access and the forced host from
+// oServer to nServer.
+func swapProxiedServer(p *mclib.Proxy, oServer, nServer *mclib.Server, sess *session.Session) (err error) {
+
   // Remove old server DNS.
   changeInfo, err := p.DetachFromProxyNetwork(oServer)
   if err != nil { return fmt.Errorf("Failed to remove server from DNS. New server up and old server not restarted: %s", err) }
@@ -217,70 +341,88 @@ func doRestartServerCmd(sess *session.Session) (err error) {
   }
   fmt.Printf("%sProxy will now forward connections for server.%s\n", successColor, resetColor )
 
-
-  // ... Kill old server task .....
-  _, err = awslib.StopTask(cluster, *oServer.TaskArn, sess)
-  if err != nil {
-    err = fmt.Errorf("Failed to stop original server task. Everything else seemed to work: %s", err)
-  }
-  fmt.Printf("%sOld server sucesfullly terminated.%s\n", successColor, resetColor)
-
-
-  // ... and report success.
-  fmt.Printf("%sServer Restarted.%s\n", successColor, resetColor)
-  serverEnv, ok  := s.ServerEnvironment()
-  if !ok { fmt.Printf("Failed to get the server Environment.") }
-  controllerEnv, ok := s.ControllerEnvironment()
-  if !ok { fmt.Printf("Failed to get the controller Environment.") }
-  w := tabwriter.NewWriter(os.Stdout, 4, 8, 8, ' ', 0)
-  fmt.Fprintf(w, "%sCluster\tUser\tName\tTask\tRegion\tBucket\tWorld%s\n", titleColor, resetColor)
-  fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
-    currentCluster, serverEnv[mclib.ServerUserKey], serverEnv[mclib.ServerNameKey], tdArn, 
-    controllerEnv[mclib.ArchiveRegionKey], controllerEnv[mclib.ArchiveBucketKey], serverEnv["WORLD"],
-    resetColor)
-  w.Flush()
-
   return err
 }
 
 
 // Set up the environment to start the server from a snapshot.
-func startServer(un, sn, region, bn, snapshotName, tdArn, clusterName string, 
-  sess *session.Session) (s *mclib.Server, err error) {
+func startServer(un, sn, region, bn, snapshotName, tdArn, clusterName string, settings map[string]string,
+  pl placement, sess *session.Session) (s *mclib.Server, err error) {
 
   ss, err := mclib.NewServerSpec(un, sn, region, bn, clusterName, tdArn, sess)
   if err != nil { return s, err }
 
+  applySettings(ss.ServerContainerEnv(), settings)
+  applySettings(ss.ServerContainerEnv(), playerSettings(clusterName, un, sn))
+  err = setRconSecret(ss.ServerContainerEnv(), clusterName, serverSecretKind, un, sn, sess)
+  if err != nil { return s, err }
   serverEnv := ss.ServerContainerEnv()
   serverEnv[mclib.WorldKey] = snapshotName
-  s, err = launchServer(ss, sess)
+  s, err = launchServer(ss, pl, sess)
   return s, err
 }
 
 
-// TODO: Figure out if this is an issue: don't launch a server if there is already 
-// one with the same user and server names. Probably only really matters in the case
-// of proxing. That said, shouldn't we just prevent this?
-func launchServer(ss mclib.ServerSpec, sess *session.Session) (s *mclib.Server, err error) {
+// checkDuplicateServer refuses to launch a server if there is already one running
+// or pending with the same user and server names, since GetServerFromName can't tell them apart.
+// With --replace we restart the existing server instead (replaced is true when we did),
+// and --allow-duplicate launches anyway.
+func checkDuplicateServer(userName, serverName, snapshot, tdArn, clusterName string, settings map[string]string,
+  pl placement, sess *session.Session) (replaced bool, err error) {
+  existing, err := findServers(userName, serverName, clusterName, sess)
+  if err != nil { return replaced, fmt.Errorf("Failed to check for an existing server: %s", err) }
+  if len(existing) == 0 { return replaced, nil }
+
+  switch {
+  case allowDuplicateFlag:
+    fmt.Printf("%sThere are already (%d) servers named %s for %s on %s, launching another anyway.%s\n",
+      warnColor, len(existing), serverName, userName, clusterName, resetColor)
+    return replaced, nil
+  case replaceFlag:
+    if len(existing) > 1 {
+      return replaced, fmt.Errorf("There are (%d) servers named %s for %s, don't know which to replace. Terminate the extras first.",
+        len(existing), serverName, userName)
+    }
+    if err = checkReplaceQuota(existing[0], tdArn, quotaOverrideFlag, sess); err != nil { return replaced, err }
+    proxies, _, err := mclib.GetProxies(clusterName, sess)
+    if err != nil { return replaced, err }
+    fmt.Printf("%sReplacing %s for %s (%s).%s\n", warnColor, serverName, userName, 
+      awslib.ShortArnString(existing[0].TaskArn), resetColor)
+    s, err := restartServer(restartSpec{
+      Server: existing[0],
+      Proxy: serverProxy(existing[0], proxies),
+      Snapshot: snapshot,
+      Env: settings,
+      TaskDef: tdArn,
+      Cluster: clusterName,
+      ReadyTimeout: readyTimeoutArg,
+      Placement: pl,
+    }, sess)
+    if err == nil { displayServer(s) }
+    return true, err
+  }
+  return replaced, fmt.Errorf("Server %s for %s is already on %s (%s). Use --replace to restart it or --allow-duplicate to launch another.",
+    serverName, userName, clusterName, awslib.ShortArnString(existing[0].TaskArn))
+}
+
+// findServers returns the running or pending servers with the user and server names.
+func findServers(userName, serverName, clusterName string, sess *session.Session) (servers []*mclib.Server, err error) {
+  all, err := getServers(clusterName, sess)
+  if err != nil { return servers, err }
+  for _, s := range all {
+    if s.User == userName && s.Name == serverName {
+      servers = append(servers, s)
+    }
+  }
+  return servers, err
+}
+
+func launchServer(ss mclib.ServerSpec, pl placement, sess *session.Session) (s *mclib.Server, err error) {
 
   startTime := time.Now()
-  s, err = ss.LaunchServer()
+  s, err = runServerTask(ss, pl, sess)
   if err != nil {
-    switch err := err.(type) {
-    case mclib.TaskError:
-      fmt.Printf("%sFailure in launch server (Task Error).%s\n", failColor, resetColor)
-      if len(err.Failures) > 0 {
-        fmt.Printf("%s(%d) failures reported.%s\n", titleColor, len(err.Failures), resetColor)
-        w := tabwriter.NewWriter(os.Stdout, 4, 8, 8, ' ', 0)
-        fmt.Fprintf(w, "%sFailure\tResource\tReason%s\n", titleColor, resetColor)
-        for i, f := range err.Failures {
-          fmt.Fprintf(w, "%s%d\t%s\t%s%s\n", nullColor, i+1, *f.Arn, *f.Reason, resetColor)
-        }
-        w.Flush()
-      }
-    default:
-      fmt.Printf("%sFailure in launch server. %#v%s\n", failColor, err, resetColor)
-    }
+    fmt.Printf("%sFailure in launch server. %s%s\n", failColor, err, resetColor)
     return s, err 
   }
 
@@ -290,8 +432,18 @@ func launchServer(ss mclib.ServerSpec, sess *session.Session) (s *mclib.Server,
         // go get the most recent data.
         ns, err  := mclib.GetServer(s.ClusterName, *s.TaskArn, sess)
         if err == nil {
-          fmt.Printf("\n%s%s for %s %s is now running (%s) on cluster: %s. %s\n",
+          fillServerAddress(ns, sess)
+          fmt.Printf("\n%s%s for %s %s is now running (%s) on cluster: %s. Waiting for it to accept logins.%s\n",
            successColor, ns.Name, ns.User, ns.ServerAddress(), time.Since(startTime), ns.ClusterName, resetColor)
+          onReady(serverProbes(ns), readyTimeoutArg, func(elapsed time.Duration, err error) {
+            if err == nil {
+              fmt.Printf("\n%s%s for %s %s is ready (%s).%s\n",
+                successColor, ns.Name, ns.User, ns.ServerAddress(), time.Since(startTime), resetColor)
+            } else {
+              fmt.Printf("\n%s%s for %s is running but not ready: %s%s\n",
+                failColor, ns.Name, ns.User, err, resetColor)
+            }
+          })
         } else {
           fmt.Printf("\n%sServer is now running for user %s on %s. (%s).%s\n",
            successColor, s.Name, s.ClusterName, time.Since(startTime), resetColor)
@@ -312,10 +464,11 @@ func doTerminateServerCmd(sess *session.Session) (error) {
   serverName := serverNameArg
   cluster := currentCluster
 
-  s, err := mclib.GetServerFromName(serverName, cluster, sess)
+  s, err := getServer(serverName, cluster, sess)
   if err != nil { return fmt.Errorf("Teriminate server failed: %s", err)}
 
   // _, err := awslib.StopTask(currentCluster, taskArn, sess)
+  expectStop(*s.TaskArn)
   taskArn, err := s.Terminate()
   if err != nil { return fmt.Errorf("terminate server failed: %s", err) }
 
@@ -364,7 +517,7 @@ func doTerminateServerCmd(sess *session.Session) (error) {
 }
 
 func doListServersCmd(clusterName string, sess *session.Session) (err error) { 
-  servers, err := mclib.GetServers(clusterName, sess)
+  servers, err := getServers(clusterName, sess)
   if err != nil {return err}
 
   fmt.Printf("%s%s servers on %s%s\n", titleColor, 
@@ -377,9 +530,29 @@ func doListServersCmd(clusterName string, sess *session.Session) (err error) {
     return nil
   } else {
     sort.Sort(mclib.ByStartAt(servers))
+    lr := make(latestRevisions)
+    counts := make(map[string]int)
+    for _, s := range servers { counts[s.User + "/" + s.Name]++ }
+    hs, err := loadHibernationStore(hibernatedFile)
+    if err != nil { return err }
     for _, s := range servers {
-      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
-        s.User, s.Name,  awslib.ShortArnString(s.DeepTask.TaskDefinition.TaskDefinitionArn), s.CraftType(), 
+      color := nullColor
+      td := awslib.ShortArnString(s.DeepTask.TaskDefinition.TaskDefinitionArn)
+      if old, latest := lr.IsOld(*s.DeepTask.TaskDefinition.TaskDefinitionArn, sess); old {
+        color = warnColor
+        td = fmt.Sprintf("%s (latest: %d)", td, latest)
+      }
+      name := s.Name
+      if counts[s.User + "/" + s.Name] > 1 {
+        color = failColor
+        name = fmt.Sprintf("%s (duplicate)", s.Name)
+      }
+      if h, ok := hs.sleeping(s); ok {
+        color = titleColor
+        name = fmt.Sprintf("%s (hibernating %s)", s.Name, awslib.ShortDurationString(time.Since(h.Since)))
+      }
+      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", color,
+        s.User, name, td, s.CraftType(), 
         s.PublicServerIp, s.PrivateServerIp, s.ServerPort, s.RconPort, awslib.ShortArnString(s.TaskArn),
         resetColor)
     }
@@ -391,23 +564,24 @@ func doListServersCmd(clusterName string, sess *session.Session) (err error) {
 
 func doStatusServersCmd(clusterName string, sess *session.Session) (err error) {
 
-  servers, err := mclib.GetServers(clusterName, sess)
+  servers, err := getServers(clusterName, sess)
   if err != nil {return err}
 
   fmt.Printf("%s%s servers on %s%s\n", titleColor, 
     time.Now().Local().Format(time.RFC1123), currentCluster, resetColor)
   w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
-  fmt.Fprintf(w, "%sUser\tServer\tTask Definition\tType\tServer\tControl\tLaunch\tUptime\tTTS%s\n", titleColor, resetColor)
+  fmt.Fprintf(w, "%sUser\tServer\tTask Definition\tType\tServer\tControl\tReady\tLaunch\tUptime\tTTS%s\n", titleColor, resetColor)
   if len(servers) == 0 {
     fmt.Fprintf(w,"%s\tNO SERVERS FOUND ON THIS CLUSTER%s\n", titleColor, resetColor)
     w.Flush()
     return nil
   } else {
     sort.Sort(mclib.ByStartAt(servers))
+    ready := readyStatus(servers)
     for _, s := range servers {
-      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
+      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
         s.User, s.Name,  awslib.ShortArnString(s.DeepTask.TaskDefinition.TaskDefinitionArn), s.CraftType(),
-        s.ServerContainerStatus(), s.ControllerContainerStatus(), s.StartedAtString(), s.UptimeString(), 
+        s.ServerContainerStatus(), s.ControllerContainerStatus(), ready[s], s.StartedAtString(), s.UptimeString(), 
         s.DeepTask.TimeToStartString(), 
         resetColor)
     }
@@ -419,13 +593,14 @@ func doStatusServersCmd(clusterName string, sess *session.Session) (err error) {
 
 func doDescribeServerCmd(serverName, clusterName string, sess *session.Session) (error) {
 
-  s, err := mclib.GetServerFromName(serverName, clusterName, sess)
+  s, err := getServer(serverName, clusterName, sess)
   if err != nil { return err }
 
   pl, _, err := mclib.GetProxies(clusterName, sess)
   if err != nil { return err }
+  for _, p := range pl { fillProxyAddress(p, clusterName, sess) }
 
-  var p *mclib.Proxy
+  var sp []*mclib.Proxy
   for _, pt := range pl {
     isProxy, err := pt.IsServerProxied(s)
     if err != nil { 
@@ -433,18 +608,19 @@ func doDescribeServerCmd(serverName, clusterName string, sess *session.Session)
       fmt.Printf("Error looking for server proxy: %s/%s", pt.Name, s.Name)
     }
     if isProxy {
-      p = pt
-      break;
+      sp = append(sp, pt)
     }
   }
 
   fqdn := "<not-available>"
   ipAddress := "<not-available>"
-  if p != nil {
-    dn, err := p.ProxiedServerFQDN(s)
+  if len(sp) > 0 {
+    dn, err := sp[0].ProxiedServerFQDN(s)
     if err == nil {
       fqdn = dn
-      ipAddress = p.PublicIpAddress()
+      ips := make([]string, len(sp))
+      for i, p := range sp { ips[i] = p.PublicIpAddress() }
+      ipAddress = strings.Join(ips, ", ")
     }
   }
 
@@ -459,24 +635,43 @@ func doDescribeServerCmd(serverName, clusterName string, sess *session.Session)
     dt.StartedAtString(), s.UptimeString(), dt.TimeToStartString(), resetColor)
   w.Flush()
 
+  // Proxies
+  fmt.Printf("\n%sProxies%s\n", titleColor, resetColor)
+  if len(sp) > 0 {
+    w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+    fmt.Fprintf(w, "%sProxy	Public IP	Task%s\n", titleColor, resetColor)
+    for _, p := range sp {
+      fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", nullColor, p.Name, p.PublicIpAddress(), awslib.ShortArnString(&p.TaskArn), resetColor)
+    }
+    w.Flush()
+  } else {
+    fmt.Printf("Not proxied.\n")
+  }
+
   // Task details
   fmt.Printf("\n%sTask%s\n", titleColor, resetColor)
   w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
   fmt.Fprintf(w, "%sTaskDefinintion\tARN\tInstnanceID\tTaskRole\tPublicIP\tPrivateIP\tNetwork Mode\tStatus%s\n", titleColor, resetColor) 
   roleArn := "<none>"
   if dt.TaskDefinition.TaskRoleArn != nil { roleArn = *dt.TaskDefinition.TaskRoleArn }
+  // Fargate tasks have no instance to get addresses from.
+  publicIP, privateIP := s.PublicServerIp, s.PrivateServerIp
+  if dt.GetInstanceID() != nil { publicIP, privateIP = dt.PublicIpAddress(), dt.PrivateIpAddress() }
+  networkMode := "<none>"
+  if dt.TaskDefinition.NetworkMode != nil { networkMode = *dt.TaskDefinition.NetworkMode }
   fmt.Fprintf(w,"%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n", nullColor,
-    awslib.ShortArnString(dt.TaskDefinition.TaskDefinitionArn), awslib.ShortArnString(s.TaskArn), *dt.GetInstanceID(), roleArn, dt.PublicIpAddress(), dt.PrivateIpAddress(), 
-    *dt.TaskDefinition.NetworkMode, dt.LastStatus(), resetColor)
+    awslib.ShortArnString(dt.TaskDefinition.TaskDefinitionArn), awslib.ShortArnString(s.TaskArn),
+    instanceDisplay(dt.GetInstanceID(), dt.Task), roleArn, publicIP, privateIP, 
+    networkMode, dt.LastStatus(), resetColor)
   w.Flush()
 
   // Volumes
   fmt.Printf("%s\nVolumes%s\n", titleColor, resetColor)
   if len(dt.TaskDefinition.Volumes) > 0 {
     w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
-    fmt.Fprintf(w, "%sName\tHost Source Path%s\n", titleColor, resetColor)
+    fmt.Fprintf(w, "%sName\tSource%s\n", titleColor, resetColor)
     for _, v := range dt.TaskDefinition.Volumes {
-      fmt.Fprintf(w,"%s%s\t%s%s\n", nullColor, *v.Name, *v.Host.SourcePath, resetColor)
+      fmt.Fprintf(w,"%s%s\t%s%s\n", nullColor, *v.Name, volumeSource(v), resetColor)
     }
   } else {
     fmt.Printf("No volumes specified.\n")
@@ -634,11 +829,12 @@ func doDescribeServerCmd(serverName, clusterName string, sess *session.Session)
   w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
   fmt.Fprintf(w, "%sContainer\tKey\tValue%s\n", titleColor, resetColor)
   for _, te := range tel {
-    fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", nullColor, te.Container, te.Key, te.Value, resetColor)
+    fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", nullColor, te.Container, te.Key, redactValue(te.Key, te.Value), resetColor)
   }
   w.Flush()
 
   // Per instance metrics
+  printServerMetrics(s, clusterName, metricsWindowArg, sess)
   return err
 }
 
@@ -707,11 +903,38 @@ func (om optionMap) String() (s string) {
 
 func doServerProxyCmd(sess *session.Session) (err error) {
 
-  s, err := mclib.GetServerFromName(serverNameArg, currentCluster, sess)
+  s, err := getServer(serverNameArg, currentCluster, sess)
   if err != nil { return err }
   p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess) 
   if err != nil { return err }
 
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+  current := serverProxies(s, proxies)
+  for _, cp := range current {
+    if cp.TaskArn == p.TaskArn { return fmt.Errorf("Server (%s) is already proxied by (%s).", s.Name, p.Name) }
+  }
+
+  if len(current) > 0 {
+    if !shareFlag {
+      return fmt.Errorf("Server (%s) is already proxied by %s. Use --share to add %s as well, or server move-proxy.",
+        s.Name, strings.Join(proxyNames(current), ", "), p.Name)
+    }
+    if _, err = sharedServerFQDN(s, append(append([]*mclib.Proxy{}, current...), p)); err != nil { return err }
+  }
+
+  // No DNS or forwarding until players can get on.
+  fmt.Printf("%sWaiting for %s to accept logins.%s\n", warnColor, s.Name, resetColor)
+  if err = waitForReady(serverProbes(s), readyTimeoutArg); err != nil {
+    return fmt.Errorf("Server (%s) is %s. Not proxied.", s.Name, err)
+  }
+
+  if len(current) > 0 {
+    if err = shareServer(s, p, current, sess); err != nil { return err }
+    fmt.Printf("%sServer shared by %s and %s.%s\n", successColor, strings.Join(proxyNames(current), ", "), p.Name, resetColor)
+    return nil
+  }
+
   if err = p.AddServerAccess(s); err != nil { return err }
   sFQDN, ci, err := p.AttachToProxyNetwork(s)
   if err != nil {
@@ -729,12 +952,41 @@ func doServerProxyCmd(sess *session.Session) (err error) {
 
 func doServerUnProxyCmd(sess *session.Session) (err error) {
 
-  s, err := mclib.GetServerFromName(serverNameArg, currentCluster, sess)
+  s, err := getServer(serverNameArg, currentCluster, sess)
   if err != nil { return err }
   p, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
   if err != nil { return err }
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+  return removeServerFromProxy(p, s, proxies, sess)
+}
+
+func doServerMoveProxyCmd(sess *session.Session) (err error) {
+  s, err := getServer(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  from, err := mclib.GetProxyFromName(proxyNameArg, currentCluster, sess)
+  if err != nil { return err }
+  to, err := mclib.GetProxyFromName(toProxyNameArg, currentCluster, sess)
+  if err != nil { return err }
 
+  proxied, err := from.IsServerProxied(s)
+  if err != nil { return err }
+  if !proxied { return fmt.Errorf("Server (%s) not proxied by (%s).", s.Name, from.Name) }
+  if proxied, err = to.IsServerProxied(s); err != nil { return err }
+  if proxied { return fmt.Errorf("Server (%s) is already proxied by (%s).", s.Name, to.Name) }
+
+  // Proxies sharing the server keep it.
+  proxies, _, err := mclib.GetProxies(currentCluster, sess)
+  if err != nil { return err }
+  others := withoutProxy(serverProxies(s, proxies), from)
+
+  if err = moveServerProxy(s, from, to, others, sess); err != nil { return err }
+  fmt.Printf("%sMoved %s from %s to %s.%s\n", successColor, s.Name, from.Name, to.Name, resetColor)
+  return nil
+}
 
+// unproxyServer removes the server's DNS, forced host and access from the proxy.
+func unproxyServer(p *mclib.Proxy, s *mclib.Server, sess *session.Session) (err error) {
   successMessages := make([]string,0)
   errorMessages := make([]string, 0)
 
@@ -0,0 +1,171 @@
+package interactive
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Server configuration.
+// Settings end up in the minecraft container's environment, where
+// the image writes them into server.properties. You can set them
+// by environment key (MOTD=...) or by server.properties name (motd=...).
+//
+
+type settingKind int
+const (
+  stringSetting settingKind = iota
+  boolSetting
+  intSetting
+  enumSetting
+)
+
+type serverSetting struct {
+  Key string          // Environment key.
+  Property string     // server.properties name, if there is one.
+  Kind settingKind
+  Values []string     // For enums.
+}
+
+var serverSettings = []serverSetting {
+  {Key: mclib.ModeKey, Property: "gamemode", Kind: enumSetting,
+    Values: []string{"survival", "creative", "adventure", "spectator", "0", "1", "2", "3"}},
+  {Key: mclib.MOTDKey, Property: "motd", Kind: stringSetting},
+  {Key: mclib.MaxPlayersKey, Property: "max-players", Kind: intSetting},
+  {Key: mclib.PVPKey, Property: "pvp", Kind: boolSetting},
+  {Key: mclib.JVMOptsKey, Kind: stringSetting},
+  {Key: mclib.ViewDistanceKey, Property: "view-distance", Kind: intSetting},
+  {Key: mclib.SpawnAnimalsKey, Property: "spawn-animals", Kind: boolSetting},
+  {Key: mclib.SpawnMonstersKey, Property: "spawn-monsters", Kind: boolSetting},
+  {Key: mclib.SpawnNPCSKey, Property: "spawn-npcs", Kind: boolSetting},
+  {Key: mclib.ForceGameModeKey, Property: "force-gamemode", Kind: boolSetting},
+  {Key: mclib.GenerateStructuresKey, Property: "generate-structures", Kind: boolSetting},
+  {Key: mclib.AllowNetherKey, Property: "allow-nether", Kind: boolSetting},
+  {Key: mclib.LevelKey, Property: "level-name", Kind: stringSetting},
+  {Key: mclib.OnlineModeKey, Property: "online-mode", Kind: boolSetting},
+  {Key: mclib.QueryKey, Property: "enable-query", Kind: boolSetting},
+  {Key: mclib.QueryPortKey, Property: "query.port", Kind: intSetting},
+}
+
+// lookupSetting finds a setting by environment key or property name.
+func lookupSetting(name string) (serverSetting, bool) {
+  for _, s := range serverSettings {
+    if strings.EqualFold(name, s.Key) || (s.Property != "" && name == s.Property) {
+      return s, true
+    }
+  }
+  return serverSetting{}, false
+}
+
+func (s serverSetting) validate(value string) (error) {
+  switch s.Kind {
+  case boolSetting:
+    if value != "true" && value != "false" {
+      return fmt.Errorf("%s must be true or false, not %q", s.Key, value)
+    }
+  case intSetting:
+    if n, err := strconv.Atoi(value); err != nil || n < 0 {
+      return fmt.Errorf("%s must be a positive number, not %q", s.Key, value)
+    }
+  case enumSetting:
+    for _, v := range s.Values {
+      if value == v { return nil }
+    }
+    return fmt.Errorf("%s must be one of %s, not %q", s.Key, strings.Join(s.Values, ", "), value)
+  }
+  return nil
+}
+
+// parseSettings builds the environment overrides from --props and --set, in that order,
+// so that --set wins.
+func parseSettings(sets []string, propsFile string) (settings map[string]string, err error) {
+  settings = make(map[string]string)
+  if propsFile != "" {
+    f, err := os.Open(propsFile)
+    if err != nil { return settings, err }
+    defer f.Close()
+    props, err := readProperties(f)
+    if err != nil { return settings, fmt.Errorf("Failed to read %s: %s", propsFile, err) }
+    for _, k := range sortedFlatKeys(props) {
+      s, ok := lookupSetting(k)
+      if !ok {
+        fmt.Printf("%sIgnoring %s from %s, it's not a setting we can pass to the server.%s\n", warnColor, k, propsFile, resetColor)
+        continue
+      }
+      if err = s.validate(props[k]); err != nil { return settings, err }
+      settings[s.Key] = props[k]
+    }
+  }
+
+  for _, kv := range sets {
+    k, v, err := splitKeyValue(kv)
+    if err != nil { return settings, fmt.Errorf("Bad --set %q, expecting KEY=VALUE.", kv) }
+    s, ok := lookupSetting(k)
+    if !ok { return settings, fmt.Errorf("Unknown server setting %q. Known settings: %s", k, knownSettings()) }
+    if err = s.validate(v); err != nil { return settings, err }
+    settings[s.Key] = v
+  }
+  return settings, nil
+}
+
+// readProperties reads a java properties file, well enough for server.properties.
+func readProperties(r io.Reader) (map[string]string, error) {
+  props := make(map[string]string)
+  scanner := bufio.NewScanner(r)
+  for scanner.Scan() {
+    line := strings.TrimSpace(scanner.Text())
+    if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") { continue }
+    i := strings.IndexAny(line, "=:")
+    if i < 0 {
+      props[line] = ""
+      continue
+    }
+    props[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+  }
+  return props, scanner.Err()
+}
+
+func knownSettings() string {
+  names := make([]string, 0, len(serverSettings))
+  for _, s := range serverSettings {
+    n := s.Key
+    if s.Property != "" { n = fmt.Sprintf("%s (%s)", s.Key, s.Property) }
+    names = append(names, n)
+  }
+  return strings.Join(names, ", ")
+}
+
+// carriedSettings are the settings from a running server's environment,
+// so that a restart keeps them.
+func carriedSettings(s *mclib.Server) (map[string]string) {
+  settings := make(map[string]string)
+  env, ok := s.ServerEnvironment()
+  if !ok { return settings }
+  for _, setting := range serverSettings {
+    if v, ok := env[setting.Key]; ok {
+      settings[setting.Key] = v
+    }
+  }
+  return settings
+}
+
+// applySettings puts the settings into the minecraft container's environment.
+func applySettings(env map[string]string, settings map[string]string) {
+  for k, v := range settings {
+    env[k] = v
+  }
+}
+
+func mergeSettings(base, overrides map[string]string) (map[string]string) {
+  m := make(map[string]string)
+  for k, v := range base { m[k] = v }
+  for k, v := range overrides { m[k] = v }
+  return m
+}
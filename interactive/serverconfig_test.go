@@ -0,0 +1,42 @@
+package interactive
+
+import (
+  "strings"
+  "testing"
+  "github.com/stretchr/testify/assert"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+func TestReadProperties(t *testing.T) {
+  props, err := readProperties(strings.NewReader(`#Minecraft server properties
+! another comment
+motd = A Minecraft Server
+max-players=20
+pvp:false
+`))
+  assert.NoError(t, err)
+  assert.Equal(t, map[string]string{"motd": "A Minecraft Server", "max-players": "20", "pvp": "false"}, props)
+}
+
+func TestParseSettings(t *testing.T) {
+  settings, err := parseSettings([]string{"motd=Hello=World", mclib.MaxPlayersKey + "=10", "pvp=false"}, "")
+  assert.NoError(t, err)
+  assert.Equal(t, "Hello=World", settings[mclib.MOTDKey])
+  assert.Equal(t, "10", settings[mclib.MaxPlayersKey])
+  assert.Equal(t, "false", settings[mclib.PVPKey])
+
+  badVals := []string{"max-players=lots", "pvp=yes", "gamemode=hardcore", "no-such-setting=1", "motd"}
+  for _, v := range badVals {
+    _, err = parseSettings([]string{v}, "")
+    assert.Error(t, err, "Expecting an error from --set %s", v)
+  }
+}
+
+func TestMergeSettings(t *testing.T) {
+  base := map[string]string{mclib.MOTDKey: "old", mclib.PVPKey: "true"}
+  m := mergeSettings(base, map[string]string{mclib.MOTDKey: "new"})
+  assert.Equal(t, map[string]string{mclib.MOTDKey: "new", mclib.PVPKey: "true"}, m)
+  assert.Equal(t, "old", base[mclib.MOTDKey])
+}
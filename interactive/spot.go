@@ -0,0 +1,124 @@
+package interactive
+
+import (
+  "encoding/json"
+  "fmt"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/aws/aws-sdk-go/service/sqs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Spot interruptions.
+// EC2 gives two minutes warning before reclaiming a spot instance. With --spot-queue
+// the watchdog reads the warnings from an SQS queue (fed by an EventBridge rule on
+// "EC2 Spot Instance Interruption Warning"), drains the instance so nothing new lands there,
+// and moves the servers and proxies on it elsewhere. Instances drained any other way
+// get the same treatment.
+//
+
+const (
+  spotInterruptionDetailType = "EC2 Spot Instance Interruption Warning"
+)
+
+type spotInterruption struct {
+  DetailType string `json:"detail-type"`
+  Detail struct {
+    InstanceID string `json:"instance-id"`
+    InstanceAction string `json:"instance-action"`
+  } `json:"detail"`
+}
+
+// parseSpotInterruption finds the instance in an interruption warning.
+func parseSpotInterruption(body string) (instanceID string, ok bool) {
+  var si spotInterruption
+  if err := json.Unmarshal([]byte(body), &si); err != nil { return instanceID, false }
+  if si.DetailType != spotInterruptionDetailType || si.Detail.InstanceID == "" { return instanceID, false }
+  return si.Detail.InstanceID, true
+}
+
+// spotInterruptions reads what's waiting on the queue, removing the messages as it goes.
+func (wd *watchdog) spotInterruptions() (instances []string, err error) {
+  if wd.SpotQueue == "" { return instances, nil }
+  svc := sqs.New(wd.sess)
+  for {
+    resp, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+      QueueUrl: aws.String(wd.SpotQueue),
+      MaxNumberOfMessages: aws.Int64(10),
+    })
+    if err != nil { return instances, err }
+    if len(resp.Messages) == 0 { return instances, nil }
+    for _, m := range resp.Messages {
+      if id, ok := parseSpotInterruption(aws.StringValue(m.Body)); ok { instances = append(instances, id) }
+      _, err = svc.DeleteMessage(&sqs.DeleteMessageInput{QueueUrl: aws.String(wd.SpotQueue), ReceiptHandle: m.ReceiptHandle})
+      if err != nil { log.Error(fmt.Sprintf("Failed to delete spot message: %s", err)) }
+    }
+  }
+}
+
+// reclaimedInstances are the EC2 instances going away: interrupted spot instances,
+// which get drained here, and anything already draining.
+func (wd *watchdog) reclaimedInstances() (reclaimed map[string]bool, err error) {
+  reclaimed = make(map[string]bool)
+  interrupted, err := wd.spotInterruptions()
+  if err != nil { return reclaimed, fmt.Errorf("Failed to read spot interruptions: %s", err) }
+  for _, id := range interrupted { reclaimed[id] = true }
+
+  cis, err := containerInstances(wd.Cluster, wd.sess)
+  if err != nil { return reclaimed, err }
+  toDrain := make([]*string, 0)
+  for _, ci := range cis {
+    id := aws.StringValue(ci.Ec2InstanceId)
+    switch {
+    case aws.StringValue(ci.Status) == ecs.ContainerInstanceStatusDraining:
+      reclaimed[id] = true
+    case reclaimed[id]:
+      toDrain = append(toDrain, ci.ContainerInstanceArn)
+    }
+  }
+  if len(toDrain) > 0 {
+    _, err = ecs.New(wd.sess).UpdateContainerInstancesState(&ecs.UpdateContainerInstancesStateInput{
+      Cluster: aws.String(wd.Cluster),
+      ContainerInstances: toDrain,
+      Status: aws.String(ecs.ContainerInstanceStatusDraining),
+    })
+    if err != nil { log.Error(fmt.Sprintf("Failed to drain interrupted instances on %s: %s", wd.Cluster, err)) }
+  }
+  return reclaimed, nil
+}
+
+// evacuate moves a task off a reclaimed instance, once.
+// Called with the lock held.
+func (wd *watchdog) evacuate(name, taskArn, instanceID string, move func() (error)) {
+  if wd.evacuated[taskArn] || wd.pending[name] { return }
+  wd.evacuated[taskArn] = true
+  wd.runNow(name, taskArn, fmt.Sprintf("%s is being reclaimed, moving", instanceID), move)
+}
+
+// evacuateServer saves the world, then restarts the server on another instance from its
+// latest snapshot, swapping proxy and DNS. There isn't time to wait for another snapshot.
+func (wd *watchdog) evacuateServer(s *mclib.Server, proxies []*mclib.Proxy) (error) {
+  if err := saveWorld(s); err != nil { fmt.Printf("%s%s%s\n", warnColor, err, resetColor) }
+  snapshot, err := latestSnapshot(s)
+  if err != nil { return err }
+  _, err = restartServer(restartSpec{
+    Server: s,
+    Proxy: serverProxy(s, proxies),
+    Snapshot: snapshot,
+    TaskDef: *s.DeepTask.TaskDefinition.TaskDefinitionArn,
+    Cluster: wd.Cluster,
+    ReadyTimeout: wd.ReadyTimeout,
+  }, wd.sess)
+  return err
+}
+
+func (wd *watchdog) evacuateProxy(p *mclib.Proxy) (error) {
+  wd.mu.Lock()
+  td := wd.proxyTaskDefs[p.Name]
+  wd.mu.Unlock()
+  _, err := replaceProxy(p, td, wd.Cluster, wd.ReadyTimeout, wd.sess)
+  return err
+}
@@ -0,0 +1,26 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestParseSpotInterruption(t *testing.T) {
+  warning := `{
+    "version": "0",
+    "detail-type": "EC2 Spot Instance Interruption Warning",
+    "source": "aws.ec2",
+    "resources": ["arn:aws:ec2:us-east-1b:instance/i-1234567890abcdef0"],
+    "detail": {"instance-id": "i-1234567890abcdef0", "instance-action": "terminate"}
+  }`
+  id, ok := parseSpotInterruption(warning)
+  assert.True(t, ok)
+  assert.Equal(t, "i-1234567890abcdef0", id)
+
+  _, ok = parseSpotInterruption(`{"detail-type": "EC2 Instance State-change Notification", "detail": {"instance-id": "i-1"}}`)
+  assert.False(t, ok)
+  _, ok = parseSpotInterruption(`{"detail-type": "EC2 Spot Instance Interruption Warning", "detail": {}}`)
+  assert.False(t, ok)
+  _, ok = parseSpotInterruption("not json")
+  assert.False(t, ok)
+}
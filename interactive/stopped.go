@@ -0,0 +1,171 @@
+package interactive
+
+import (
+  "fmt"
+  "os"
+  "sort"
+  "strings"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Why did my server stop?
+// ECS keeps stopped tasks around for a while (about an hour),
+// we look through those for the server.
+//
+
+const defaultWhyLines = "20"
+
+func doServerWhyCmd(sess *session.Session) (error) {
+  tasks, err := stoppedServerTasks(serverNameArg, currentCluster, sess)
+  if err != nil { return err }
+  if len(tasks) == 0 {
+    fmt.Printf("%sNo recently stopped tasks found for %s on %s.%s\n", warnColor, serverNameArg, currentCluster, resetColor)
+    return nil
+  }
+  if whyCountArg > 0 && len(tasks) > whyCountArg {
+    tasks = tasks[:whyCountArg]
+  }
+  for _, t := range tasks {
+    describeStoppedTask(t, whyLinesArg, sess)
+  }
+  return nil
+}
+
+// stoppedServerTasks returns the stopped tasks that ran the named server, most recent first.
+// A task arn (or task id) is described directly.
+func stoppedServerTasks(serverOrTask, clusterName string, sess *session.Session) (tasks []*ecs.Task, err error) {
+  if looksLikeTask(serverOrTask) {
+    t, err := describeTask(clusterName, serverOrTask, sess)
+    if err != nil { return tasks, err }
+    return []*ecs.Task{t}, nil
+  }
+
+  all, err := describeTasksWithStatus(clusterName, ecs.DesiredStatusStopped, sess)
+  if err != nil { return tasks, err }
+  for _, t := range all {
+    if taskEnvValue(t, mclib.ServerNameKey) == serverOrTask {
+      tasks = append(tasks, t)
+    }
+  }
+  sort.Sort(byStoppedAt(tasks))
+  return tasks, err
+}
+
+func looksLikeTask(s string) bool {
+  if strings.HasPrefix(s, "arn:") { return true }
+  // Task ids are uuids.
+  return len(s) == 36 && strings.Count(s, "-") == 4
+}
+
+// describeTasksWithStatus lists and describes all the tasks on a cluster with the desired status.
+func describeTasksWithStatus(clusterName, desiredStatus string, sess *session.Session) (tasks []*ecs.Task, err error) {
+  ecsSvc := ecs.New(sess)
+  arns := make([]*string, 0)
+  err = ecsSvc.ListTasksPages(&ecs.ListTasksInput{
+    Cluster: aws.String(clusterName),
+    DesiredStatus: aws.String(desiredStatus),
+  }, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+    arns = append(arns, page.TaskArns...)
+    return true
+  })
+  if err != nil { return tasks, err }
+
+  // DescribeTasks takes at most 100 at a time.
+  for i := 0; i < len(arns); i += 100 {
+    end := i + 100
+    if end > len(arns) { end = len(arns) }
+    resp, err := ecsSvc.DescribeTasks(&ecs.DescribeTasksInput{
+      Cluster: aws.String(clusterName),
+      Tasks: arns[i:end],
+    })
+    if err != nil { return tasks, err }
+    tasks = append(tasks, resp.Tasks...)
+  }
+  return tasks, err
+}
+
+// taskEnvValue looks through the container overrides for an environment key.
+// This is where our launch environment ends up.
+func taskEnvValue(t *ecs.Task, key string) string {
+  if t.Overrides == nil { return "" }
+  for _, co := range t.Overrides.ContainerOverrides {
+    for _, kv := range co.Environment {
+      if kv.Name != nil && *kv.Name == key && kv.Value != nil {
+        return *kv.Value
+      }
+    }
+  }
+  return ""
+}
+
+type byStoppedAt []*ecs.Task
+func (t byStoppedAt) Len() int { return len(t) }
+func (t byStoppedAt) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byStoppedAt) Less(i, j int) bool {
+  if t[i].StoppedAt == nil { return false }
+  if t[j].StoppedAt == nil { return true }
+  return t[i].StoppedAt.After(*t[j].StoppedAt)
+}
+
+func describeStoppedTask(t *ecs.Task, lines int64, sess *session.Session) {
+  stoppedAt := "<still-running>"
+  if t.StoppedAt != nil { stoppedAt = t.StoppedAt.Local().Format(time.RFC1123) }
+  ran := "<unknown>"
+  if t.StartedAt != nil && t.StoppedAt != nil { ran = awslib.ShortDurationString(t.StoppedAt.Sub(*t.StartedAt)) }
+  reason := "<none>"
+  if t.StoppedReason != nil { reason = *t.StoppedReason }
+
+  fmt.Printf("\n%sTask %s (%s) stopped %s after %s.%s\n", titleColor, awslib.ShortArnString(t.TaskArn),
+    taskEnvValue(t, mclib.ServerNameKey), stoppedAt, ran, resetColor)
+  fmt.Printf("%sReason: %s%s\n", failColor, reason, resetColor)
+
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sContainer\tStatus\tExit Code\tReason%s\n", titleColor, resetColor)
+  for _, c := range t.Containers {
+    code := "-"
+    if c.ExitCode != nil { code = fmt.Sprintf("%d", *c.ExitCode) }
+    creason := "<none>"
+    if c.Reason != nil { creason = *c.Reason }
+    status := "<none>"
+    if c.LastStatus != nil { status = *c.LastStatus }
+    color := nullColor
+    if c.ExitCode != nil && *c.ExitCode != 0 { color = failColor }
+    fmt.Fprintf(w, "%s%s\t%s\t%s\t%s%s\n", color, *c.Name, status, code, creason, resetColor)
+  }
+  w.Flush()
+
+  if lines <= 0 { return }
+  ecsSvc := ecs.New(sess)
+  tdResp, err := ecsSvc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: t.TaskDefinitionArn})
+  if err != nil {
+    fmt.Printf("%sCan't get logs, failed to get task definition: %s%s\n", warnColor, err, resetColor)
+    return
+  }
+  for _, c := range t.Containers {
+    loc, err := containerLogLocation(*c.Name, *t.TaskArn, tdResp.TaskDefinition)
+    if err != nil {
+      fmt.Printf("\n%s%s%s\n", warnColor, err, resetColor)
+      continue
+    }
+    events, err := lastLogEvents(loc, "", lines, sess)
+    if err != nil {
+      fmt.Printf("\n%sFailed to get logs for %s: %s%s\n", warnColor, *c.Name, err, resetColor)
+      continue
+    }
+    fmt.Printf("\n%sLast %d log lines for %s (%s:%s)%s\n", titleColor, len(events), *c.Name, loc.Group, loc.Stream, resetColor)
+    for _, e := range events {
+      fmt.Printf("%s\n", *e.Message)
+    }
+  }
+}
@@ -0,0 +1,483 @@
+package interactive
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "text/template"
+  "time"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+//
+// Task definitions.
+// We render new revisions either from an existing task definition
+// or from a JSON template (the same shape as aws ecs register-task-definition --cli-input-json),
+// and then apply any overrides from the command line.
+//
+
+// What we let you change from the command line.
+type taskDefParams struct {
+  Family string
+  Container string
+  ImageTag string
+  Memory int64
+  MemoryReservation int64
+  JVMOpts string
+  Volumes []string      // name=/host/path
+  EFSVolumes []string   // name=fs-id or name=fs-id:/root/dir
+  Ulimits []string      // name=soft:hard
+  LogGroup string
+  LogPrefix string
+  LogRegion string
+  Fargate bool
+  TaskCPU string        // Task level, Fargate needs these.
+  TaskMemory string
+  ExecutionRole string
+}
+
+func doTaskDefListCmd(sess *session.Session) (error) {
+  ecsSvc := ecs.New(sess)
+  params := &ecs.ListTaskDefinitionsInput{
+    Status: aws.String(ecs.TaskDefinitionStatusActive),
+    Sort: aws.String(ecs.SortOrderDesc),
+  }
+  if taskDefFamilyArg != "" { params.FamilyPrefix = aws.String(taskDefFamilyArg) }
+  arns := make([]string, 0)
+  err := ecsSvc.ListTaskDefinitionsPages(params, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+    arns = append(arns, aws.StringValueSlice(page.TaskDefinitionArns)...)
+    return true
+  })
+  if err != nil { return err }
+
+  fmt.Printf("%s%s active task definitions%s\n", titleColor, time.Now().Local().Format(time.RFC1123), resetColor)
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sFamily\tRevision\tArn%s\n", titleColor, resetColor)
+  for _, arn := range arns {
+    family, revision := splitTaskDefinition(arn)
+    fmt.Fprintf(w, "%s%s\t%d\t%s%s\n", nullColor, family, revision, arn, resetColor)
+  }
+  w.Flush()
+  return nil
+}
+
+func doTaskDefShowCmd(sess *session.Session) (error) {
+  td, err := getTaskDefinition(taskDefArg, sess)
+  if err != nil { return err }
+  flat, err := flattenTaskDefinition(td)
+  if err != nil { return err }
+  if !taskDefRevealFlag { redactFlat(flat) }
+
+  fmt.Printf("%s%s%s\n", titleColor, *td.TaskDefinitionArn, resetColor)
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sKey\tValue%s\n", titleColor, resetColor)
+  for _, k := range sortedFlatKeys(flat) {
+    fmt.Fprintf(w, "%s%s\t%s%s\n", nullColor, k, flat[k], resetColor)
+  }
+  w.Flush()
+  return nil
+}
+
+func doTaskDefRegisterCmd(sess *session.Session) (error) {
+  params := taskDefParams{
+    Family: taskDefFamilyArg,
+    Container: taskDefContainerArg,
+    ImageTag: taskDefImageTagArg,
+    Memory: taskDefMemoryArg,
+    MemoryReservation: taskDefMemoryReservationArg,
+    JVMOpts: taskDefJVMOptsArg,
+    Volumes: taskDefVolumesArg,
+    EFSVolumes: taskDefEFSVolumesArg,
+    Ulimits: taskDefUlimitsArg,
+    LogGroup: taskDefLogGroupArg,
+    LogPrefix: taskDefLogPrefixArg,
+    LogRegion: taskDefLogRegionArg,
+    Fargate: taskDefFargateFlag,
+    TaskCPU: taskDefTaskCPUArg,
+    TaskMemory: taskDefTaskMemoryArg,
+    ExecutionRole: taskDefExecutionRoleArg,
+  }
+
+  var input *ecs.RegisterTaskDefinitionInput
+  var err error
+  switch {
+  case taskDefTemplateArg != "":
+    input, err = renderTaskDefTemplate(taskDefTemplateArg, params)
+  case taskDefFromArg != "":
+    var td *ecs.TaskDefinition
+    td, err = getTaskDefinition(taskDefFromArg, sess)
+    if err == nil { input = registerInputFromTaskDefinition(td) }
+  default:
+    err = fmt.Errorf("Need either --from <task-definition> or --template <file> to register a task definition.")
+  }
+  if err != nil { return err }
+
+  if err = applyTaskDefParams(input, params); err != nil { return err }
+  if err = input.Validate(); err != nil { return err }
+
+  resp, err := ecs.New(sess).RegisterTaskDefinition(input)
+  if err != nil { return err }
+  fmt.Printf("%sRegistered %s.%s\n", successColor, *resp.TaskDefinition.TaskDefinitionArn, resetColor)
+  return nil
+}
+
+func doTaskDefDiffCmd(sess *session.Session) (error) {
+  a, err := getTaskDefinition(taskDefArg, sess)
+  if err != nil { return err }
+  b, err := getTaskDefinition(taskDefOtherArg, sess)
+  if err != nil { return err }
+  fa, err := flattenTaskDefinition(a)
+  if err != nil { return err }
+  fb, err := flattenTaskDefinition(b)
+  if err != nil { return err }
+  if !taskDefRevealFlag {
+    redactFlat(fa)
+    redactFlat(fb)
+  }
+
+  diffs := diffFlat(fa, fb)
+  fmt.Printf("%s%s => %s%s\n", titleColor, *a.TaskDefinitionArn, *b.TaskDefinitionArn, resetColor)
+  if len(diffs) == 0 {
+    fmt.Printf("No differences.\n")
+    return nil
+  }
+  printFlatDiffs(diffs, "Key")
+  return nil
+}
+
+func printFlatDiffs(diffs []flatDiff, keyTitle string) {
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%s%s\tFrom\tTo%s\n", titleColor, keyTitle, resetColor)
+  for _, d := range diffs {
+    from, to := d.From, d.To
+    if from == "" { from = "<none>" }
+    if to == "" { to = "<none>" }
+    fmt.Fprintf(w, "%s%s\t%s\t%s%s\n", warnColor, d.Key, from, to, resetColor)
+  }
+  w.Flush()
+}
+
+func getTaskDefinition(td string, sess *session.Session) (*ecs.TaskDefinition, error) {
+  resp, err := ecs.New(sess).DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+    TaskDefinition: aws.String(td),
+  })
+  if err != nil { return nil, err }
+  return resp.TaskDefinition, nil
+}
+
+// splitTaskDefinition: arn:...:task-definition/minecraft:12 => minecraft, 12
+func splitTaskDefinition(tdArn string) (family string, revision int64) {
+  family = taskDefinitionFamily(tdArn)
+  s := taskID(tdArn)
+  if i := strings.LastIndex(s, ":"); i >= 0 {
+    revision, _ = strconv.ParseInt(s[i+1:], 10, 64)
+  }
+  return family, revision
+}
+
+func renderTaskDefTemplate(fileName string, params taskDefParams) (input *ecs.RegisterTaskDefinitionInput, err error) {
+  text, err := ioutil.ReadFile(fileName)
+  if err != nil { return input, err }
+  t, err := template.New(fileName).Parse(string(text))
+  if err != nil { return input, err }
+  b := new(bytes.Buffer)
+  if err = t.Execute(b, params); err != nil { return input, err }
+  input = new(ecs.RegisterTaskDefinitionInput)
+  err = json.Unmarshal(b.Bytes(), input)
+  if err != nil { err = fmt.Errorf("Failed to read rendered template %s: %s", fileName, err) }
+  return input, err
+}
+
+func registerInputFromTaskDefinition(td *ecs.TaskDefinition) (*ecs.RegisterTaskDefinitionInput) {
+  return &ecs.RegisterTaskDefinitionInput{
+    Family: td.Family,
+    ContainerDefinitions: td.ContainerDefinitions,
+    Volumes: td.Volumes,
+    NetworkMode: td.NetworkMode,
+    TaskRoleArn: td.TaskRoleArn,
+    PlacementConstraints: td.PlacementConstraints,
+    RequiresCompatibilities: td.RequiresCompatibilities,
+    Cpu: td.Cpu,
+    Memory: td.Memory,
+    ExecutionRoleArn: td.ExecutionRoleArn,
+  }
+}
+
+// applyTaskDefParams sets anything given on the command line on
+// the task definition, container changes go to params.Container.
+func applyTaskDefParams(input *ecs.RegisterTaskDefinitionInput, params taskDefParams) (error) {
+  if params.Family != "" { input.Family = aws.String(params.Family) }
+  if params.TaskCPU != "" { input.Cpu = aws.String(params.TaskCPU) }
+  if params.TaskMemory != "" { input.Memory = aws.String(params.TaskMemory) }
+  if params.ExecutionRole != "" { input.ExecutionRoleArn = aws.String(params.ExecutionRole) }
+  if params.Fargate { makeFargate(input) }
+
+  for _, v := range params.EFSVolumes {
+    name, efs, err := parseEFSVolume(v)
+    if err != nil { return err }
+    set := false
+    for _, vol := range input.Volumes {
+      if *vol.Name == name {
+        vol.Host, vol.EfsVolumeConfiguration, set = nil, efs, true
+      }
+    }
+    if !set { input.Volumes = append(input.Volumes, &ecs.Volume{Name: aws.String(name), EfsVolumeConfiguration: efs}) }
+  }
+
+  var cdef *ecs.ContainerDefinition
+  for _, c := range input.ContainerDefinitions {
+    if c.Name != nil && *c.Name == params.Container { cdef = c }
+  }
+  containerChanges := params.ImageTag != "" || params.Memory > 0 || params.MemoryReservation > 0 ||
+    params.JVMOpts != "" || len(params.Ulimits) > 0 || params.LogGroup != "" || len(params.Volumes) > 0
+  if cdef == nil {
+    if containerChanges { return fmt.Errorf("No container named %s in the task definition.", params.Container) }
+    return nil
+  }
+
+  if params.ImageTag != "" && cdef.Image != nil {
+    cdef.Image = aws.String(imageWithTag(*cdef.Image, params.ImageTag))
+  }
+  if params.Memory > 0 { cdef.Memory = aws.Int64(params.Memory) }
+  if params.MemoryReservation > 0 { cdef.MemoryReservation = aws.Int64(params.MemoryReservation) }
+  if params.JVMOpts != "" { setContainerEnv(cdef, mclib.JVMOptsKey, params.JVMOpts) }
+
+  for _, ul := range params.Ulimits {
+    name, soft, hard, err := parseUlimit(ul)
+    if err != nil { return err }
+    set := false
+    for _, u := range cdef.Ulimits {
+      if *u.Name == name {
+        u.SoftLimit, u.HardLimit, set = aws.Int64(soft), aws.Int64(hard), true
+      }
+    }
+    if !set {
+      cdef.Ulimits = append(cdef.Ulimits, &ecs.Ulimit{Name: aws.String(name), SoftLimit: aws.Int64(soft), HardLimit: aws.Int64(hard)})
+    }
+  }
+
+  for _, v := range params.Volumes {
+    name, path, err := splitKeyValue(v)
+    if err != nil { return fmt.Errorf("Bad volume %q, expecting name=/host/path: %s", v, err) }
+    set := false
+    for _, vol := range input.Volumes {
+      if *vol.Name == name {
+        vol.Host, vol.EfsVolumeConfiguration, set = &ecs.HostVolumeProperties{SourcePath: aws.String(path)}, nil, true
+      }
+    }
+    if !set {
+      input.Volumes = append(input.Volumes, &ecs.Volume{Name: aws.String(name),
+        Host: &ecs.HostVolumeProperties{SourcePath: aws.String(path)}})
+    }
+  }
+
+  if params.LogGroup != "" {
+    options := map[string]*string{awsLogsGroupOption: aws.String(params.LogGroup)}
+    if params.LogPrefix != "" { options[awsLogsPrefixOption] = aws.String(params.LogPrefix) }
+    if params.LogRegion != "" { options[awsLogsRegionOption] = aws.String(params.LogRegion) }
+    cdef.LogConfiguration = &ecs.LogConfiguration{LogDriver: aws.String(awsLogsDriver), Options: options}
+  }
+  return nil
+}
+
+// imageWithTag replaces the tag on an image: repo:tag, repo, host:port/repo:tag.
+func imageWithTag(image, tag string) string {
+  if i := strings.Index(image, "@"); i >= 0 { image = image[:i] }
+  slash := strings.LastIndex(image, "/")
+  if colon := strings.LastIndex(image, ":"); colon > slash {
+    image = image[:colon]
+  }
+  return image + ":" + tag
+}
+
+// makeFargate switches to awsvpc networking, where the host port is the container port.
+func makeFargate(input *ecs.RegisterTaskDefinitionInput) {
+  input.RequiresCompatibilities = aws.StringSlice([]string{ecs.CompatibilityFargate})
+  input.NetworkMode = aws.String(ecs.NetworkModeAwsvpc)
+  for _, cd := range input.ContainerDefinitions {
+    for _, pm := range cd.PortMappings { pm.HostPort = pm.ContainerPort }
+    // No links with awsvpc, the containers share localhost.
+    cd.Links = nil
+  }
+}
+
+// parseEFSVolume: name=fs-id or name=fs-id:/root/dir
+func parseEFSVolume(s string) (name string, efs *ecs.EFSVolumeConfiguration, err error) {
+  name, fs, err := splitKeyValue(s)
+  if err != nil { return name, efs, fmt.Errorf("Bad EFS volume %q, expecting name=fs-id[:/root/dir]: %s", s, err) }
+  root := ""
+  if i := strings.Index(fs, ":"); i >= 0 { fs, root = fs[:i], fs[i+1:] }
+  if !strings.HasPrefix(fs, "fs-") { return name, efs, fmt.Errorf("Bad EFS volume %q, %s isn't a file system id.", s, fs) }
+  efs = &ecs.EFSVolumeConfiguration{
+    FileSystemId: aws.String(fs),
+    TransitEncryption: aws.String(ecs.EFSTransitEncryptionEnabled),
+  }
+  if root != "" { efs.RootDirectory = aws.String(root) }
+  return name, efs, nil
+}
+
+// volumeSource describes where a volume comes from.
+func volumeSource(v *ecs.Volume) string {
+  switch {
+  case v.EfsVolumeConfiguration != nil:
+    efs := v.EfsVolumeConfiguration
+    return "efs:" + aws.StringValue(efs.FileSystemId) + aws.StringValue(efs.RootDirectory)
+  case v.Host != nil && v.Host.SourcePath != nil:
+    return *v.Host.SourcePath
+  }
+  return "<docker>"
+}
+
+func setContainerEnv(cdef *ecs.ContainerDefinition, key, value string) {
+  for _, kv := range cdef.Environment {
+    if kv.Name != nil && *kv.Name == key {
+      kv.Value = aws.String(value)
+      return
+    }
+  }
+  cdef.Environment = append(cdef.Environment, &ecs.KeyValuePair{Name: aws.String(key), Value: aws.String(value)})
+}
+
+func parseUlimit(s string) (name string, soft, hard int64, err error) {
+  name, limits, err := splitKeyValue(s)
+  if err != nil { return name, soft, hard, fmt.Errorf("Bad ulimit %q, expecting name=soft:hard: %s", s, err) }
+  parts := strings.Split(limits, ":")
+  if len(parts) != 2 { return name, soft, hard, fmt.Errorf("Bad ulimit %q, expecting name=soft:hard.", s) }
+  if soft, err = strconv.ParseInt(parts[0], 10, 64); err != nil { return name, soft, hard, err }
+  hard, err = strconv.ParseInt(parts[1], 10, 64)
+  return name, soft, hard, err
+}
+
+func splitKeyValue(s string) (k, v string, err error) {
+  i := strings.Index(s, "=")
+  if i <= 0 { return k, v, fmt.Errorf("missing \"=\" in %q", s) }
+  return s[:i], s[i+1:], nil
+}
+
+//
+// Flattening for show and diff.
+//
+
+// These change with every revision, so they aren't interesting in a diff.
+var taskDefIgnoredKeys = map[string]bool {
+  "TaskDefinitionArn": true,
+  "Revision": true,
+  "Status": true,
+  "RequiresAttributes": true,
+  "RegisteredAt": true,
+  "RegisteredBy": true,
+  "DeregisteredAt": true,
+}
+
+// flattenTaskDefinition turns a task definition into path => value.
+// Containers, volumes and environment entries are keyed by name rather than
+// position so that reordering doesn't look like a change.
+func flattenTaskDefinition(td *ecs.TaskDefinition) (map[string]string, error) {
+  b, err := json.Marshal(td)
+  if err != nil { return nil, err }
+  var v interface{}
+  if err = json.Unmarshal(b, &v); err != nil { return nil, err }
+  flat := make(map[string]string)
+  if m, ok := v.(map[string]interface{}); ok {
+    for k := range taskDefIgnoredKeys { delete(m, k) }
+  }
+  flattenValue("", v, flat)
+  return flat, nil
+}
+
+// redactFlat hides secret looking values, by the last part of their key,
+// e.g. ContainerDefinitions.minecraft.Environment.RCON_PASSWORD.
+// A changed secret still shows up in a diff, as redacted on both sides.
+func redactFlat(flat map[string]string) {
+  for k, v := range flat {
+    flat[k] = redactValue(k[strings.LastIndex(k, ".")+1:], v)
+  }
+}
+
+func flattenValue(prefix string, v interface{}, flat map[string]string) {
+  join := func(k string) string {
+    if prefix == "" { return k }
+    return prefix + "." + k
+  }
+  switch v := v.(type) {
+  case map[string]interface{}:
+    for k, e := range v {
+      flattenValue(join(k), e, flat)
+    }
+  case []interface{}:
+    for i, e := range v {
+      key := strconv.Itoa(i)
+      if m, ok := e.(map[string]interface{}); ok {
+        if name, ok := m["Name"].(string); ok {
+          key = name
+          // KeyValuePairs flatten to the value.
+          if value, ok := m["Value"]; ok && len(m) == 2 {
+            flattenValue(join(key), value, flat)
+            continue
+          }
+        }
+      }
+      flattenValue(join(key), e, flat)
+    }
+  case nil:
+  default:
+    flat[prefix] = fmt.Sprintf("%v", v)
+  }
+}
+
+type flatDiff struct {
+  Key string
+  From string
+  To string
+}
+
+func diffFlat(a, b map[string]string) (diffs []flatDiff) {
+  keys := make(map[string]bool)
+  for k := range a { keys[k] = true }
+  for k := range b { keys[k] = true }
+  for k := range keys {
+    if a[k] != b[k] {
+      diffs = append(diffs, flatDiff{Key: k, From: a[k], To: b[k]})
+    }
+  }
+  sort.Sort(byDiffKey(diffs))
+  return diffs
+}
+
+type byDiffKey []flatDiff
+func (d byDiffKey) Len() int { return len(d) }
+func (d byDiffKey) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d byDiffKey) Less(i, j int) bool { return d[i].Key < d[j].Key }
+
+func sortedFlatKeys(m map[string]string) (s []string) {
+  s = make([]string, 0, len(m))
+  for k := range m { s = append(s, k) }
+  sort.Strings(s)
+  return s
+}
+
+// latestRevisions finds the latest active revision for each family, caching as it goes.
+type latestRevisions map[string]int64
+
+func (lr latestRevisions) IsOld(tdArn string, sess *session.Session) (old bool, latest int64) {
+  family, revision := splitTaskDefinition(tdArn)
+  latest, ok := lr[family]
+  if !ok {
+    td, err := getTaskDefinition(family, sess)
+    if err != nil { return false, revision }
+    latest = *td.Revision
+    lr[family] = latest
+  }
+  return revision < latest, latest
+}
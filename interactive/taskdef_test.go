@@ -0,0 +1,133 @@
+package interactive
+
+import (
+  "testing"
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/ecs"
+  "github.com/stretchr/testify/assert"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+)
+
+func TestImageWithTag(t *testing.T) {
+  testVals := [][]string {
+    { "jdrivas/minecraft", "1.10", "jdrivas/minecraft:1.10", },
+    { "jdrivas/minecraft:latest", "1.10", "jdrivas/minecraft:1.10", },
+    { "registry:5000/minecraft", "1.10", "registry:5000/minecraft:1.10", },
+    { "registry:5000/minecraft:old", "1.10", "registry:5000/minecraft:1.10", },
+  }
+  for _, v := range testVals {
+    assert.Equal(t, v[2], imageWithTag(v[0], v[1]), "Expecting %s tagged %s to yield %s", v[0], v[1], v[2])
+  }
+}
+
+func TestParseUlimit(t *testing.T) {
+  name, soft, hard, err := parseUlimit("nofile=1024:4096")
+  assert.NoError(t, err)
+  assert.Equal(t, "nofile", name)
+  assert.Equal(t, int64(1024), soft)
+  assert.Equal(t, int64(4096), hard)
+
+  for _, bad := range []string{"nofile", "nofile=1024", "=1:2", "nofile=a:b"} {
+    _, _, _, err = parseUlimit(bad)
+    assert.Error(t, err, "Expecting %q to fail.", bad)
+  }
+}
+
+func testTaskDefinition(revision int64, image, jvmOpts string) *ecs.TaskDefinition {
+  return &ecs.TaskDefinition{
+    TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/minecraft:1"),
+    Family: aws.String("minecraft"),
+    Revision: aws.Int64(revision),
+    ContainerDefinitions: []*ecs.ContainerDefinition{
+      {
+        Name: aws.String(serverContainerName),
+        Image: aws.String(image),
+        Memory: aws.Int64(1024),
+        Environment: []*ecs.KeyValuePair{{Name: aws.String(mclib.JVMOptsKey), Value: aws.String(jvmOpts)}},
+      },
+    },
+  }
+}
+
+func TestDiffTaskDefinitions(t *testing.T) {
+  fa, err := flattenTaskDefinition(testTaskDefinition(1, "minecraft:1.9", "-Xmx1G"))
+  assert.NoError(t, err)
+  fb, err := flattenTaskDefinition(testTaskDefinition(2, "minecraft:1.10", "-Xmx1G"))
+  assert.NoError(t, err)
+
+  diffs := diffFlat(fa, fb)
+  if assert.Len(t, diffs, 1, "Expecting revision changes to be ignored.") {
+    assert.Equal(t, "ContainerDefinitions.minecraft.Image", diffs[0].Key)
+    assert.Equal(t, "minecraft:1.9", diffs[0].From)
+    assert.Equal(t, "minecraft:1.10", diffs[0].To)
+  }
+  assert.Equal(t, "-Xmx1G", fa["ContainerDefinitions.minecraft.Environment."+mclib.JVMOptsKey])
+}
+
+func TestApplyTaskDefParams(t *testing.T) {
+  input := registerInputFromTaskDefinition(testTaskDefinition(1, "minecraft:1.9", "-Xmx1G"))
+  err := applyTaskDefParams(input, taskDefParams{
+    Container: serverContainerName,
+    ImageTag: "1.10",
+    Memory: 2048,
+    JVMOpts: "-Xmx2G",
+    Ulimits: []string{"nofile=1024:4096"},
+    Volumes: []string{"world=/data/world"},
+  })
+  assert.NoError(t, err)
+  cdef := input.ContainerDefinitions[0]
+  assert.Equal(t, "minecraft:1.10", *cdef.Image)
+  assert.Equal(t, int64(2048), *cdef.Memory)
+  assert.Equal(t, "-Xmx2G", *cdef.Environment[0].Value)
+  assert.Len(t, cdef.Ulimits, 1)
+  assert.Equal(t, "/data/world", *input.Volumes[0].Host.SourcePath)
+
+  err = applyTaskDefParams(input, taskDefParams{Container: "nope", ImageTag: "1.10"})
+  assert.Error(t, err)
+}
+
+func TestApplyTaskDefParamsFargate(t *testing.T) {
+  input := registerInputFromTaskDefinition(testTaskDefinition(1, "minecraft:1.9", "-Xmx1G"))
+  input.Volumes = []*ecs.Volume{{Name: aws.String("world"), Host: &ecs.HostVolumeProperties{SourcePath: aws.String("/data/world")}}}
+  input.ContainerDefinitions[0].PortMappings = []*ecs.PortMapping{{ContainerPort: aws.Int64(25565), HostPort: aws.Int64(0)}}
+  err := applyTaskDefParams(input, taskDefParams{
+    Container: serverContainerName,
+    Fargate: true,
+    TaskCPU: "1024",
+    TaskMemory: "4096",
+    EFSVolumes: []string{"world=fs-1234:/worlds/a", "backups=fs-1234"},
+  })
+  assert.NoError(t, err)
+  assert.Equal(t, ecs.NetworkModeAwsvpc, *input.NetworkMode)
+  assert.Equal(t, []string{ecs.CompatibilityFargate}, aws.StringValueSlice(input.RequiresCompatibilities))
+  assert.Equal(t, "1024", *input.Cpu)
+  assert.Equal(t, int64(25565), *input.ContainerDefinitions[0].PortMappings[0].HostPort)
+  if assert.Len(t, input.Volumes, 2) {
+    assert.Nil(t, input.Volumes[0].Host)
+    assert.Equal(t, "efs:fs-1234/worlds/a", volumeSource(input.Volumes[0]))
+    assert.Equal(t, "efs:fs-1234", volumeSource(input.Volumes[1]))
+  }
+
+  for _, v := range []string{"world", "world=/data", "world=fs-1234:"} {
+    _, _, err := parseEFSVolume(v)
+    if v == "world=fs-1234:" {
+      assert.NoError(t, err)
+    } else {
+      assert.Error(t, err, "Expecting %q to be rejected", v)
+    }
+  }
+}
+
+func TestRedactFlat(t *testing.T) {
+  flat := map[string]string{
+    "ContainerDefinitions.minecraft.Environment.RCON_PASSWORD": "hunter2",
+    "ContainerDefinitions.minecraft.Environment.MOTD": "hello",
+    "ContainerDefinitions.secret-store.Environment.MOTD": "hi",
+  }
+  redactFlat(flat)
+  assert.Equal(t, redactedValue, flat["ContainerDefinitions.minecraft.Environment.RCON_PASSWORD"])
+  assert.Equal(t, "hello", flat["ContainerDefinitions.minecraft.Environment.MOTD"])
+  assert.Equal(t, "hi", flat["ContainerDefinitions.secret-store.Environment.MOTD"])
+}
@@ -0,0 +1,484 @@
+package interactive
+
+import (
+  "fmt"
+  "os"
+  "strings"
+  "sync"
+  "text/tabwriter"
+  "time"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/ec2"
+  "github.com/aws/aws-sdk-go/service/ecs"
+
+  // "mclib"
+  "github.com/jdrivas/mclib"
+
+  // "awslib"
+  "github.com/jdrivas/awslib"
+)
+
+//
+// Watchdog.
+// Polls a cluster for server and proxy tasks that have stopped
+// without anyone asking them to, and relaunches them.
+// It also moves them off instances that are about to go, see spot.go.
+//
+
+const (
+  defaultWatchInterval = "30s"
+  defaultWatchBackoff = "30s"
+  defaultWatchMaxAttempts = "3"
+
+  // A task that ran at least this long before stopping resets the attempt count.
+  watchStableAfter = 10 * time.Minute
+
+  // Expected stops nobody's watching for are forgotten after this.
+  expectedStopTTL = time.Hour
+)
+
+var (
+  // Tasks we've stopped on purpose, so the watchdog leaves them alone.
+  expectedStops = make(map[string]time.Time)
+  expectedStopsMu sync.Mutex
+
+  currentWatchdog *watchdog
+)
+
+// expectStop records that we're about to stop a task.
+func expectStop(taskArn string) {
+  expectedStopsMu.Lock()
+  defer expectedStopsMu.Unlock()
+  for arn, at := range expectedStops {
+    if time.Since(at) > expectedStopTTL { delete(expectedStops, arn) }
+  }
+  expectedStops[taskArn] = time.Now()
+}
+
+// takeExpectedStop says whether we stopped the task on purpose, forgetting it.
+func takeExpectedStop(taskArn string) bool {
+  expectedStopsMu.Lock()
+  defer expectedStopsMu.Unlock()
+  _, ok := expectedStops[taskArn]
+  delete(expectedStops, taskArn)
+  return ok
+}
+
+// stoppedTask is what ECS says about a task that's gone.
+type stoppedTask struct {
+  Task *ecs.Task
+  Err error
+}
+
+type watchEvent struct {
+  Time time.Time
+  Name string
+  TaskArn string
+  What string
+}
+
+type watchdog struct {
+  Cluster string
+  Interval time.Duration
+  Backoff time.Duration
+  MaxAttempts int
+  ReadyTimeout time.Duration
+  SpotQueue string                    // SQS queue URL for spot interruption warnings.
+  IdleAfter time.Duration             // Hibernate servers empty this long, 0 to leave them be.
+  SleeperTaskDef string
+  Started time.Time
+
+  sess *session.Session
+  stop chan bool
+
+  mu sync.Mutex
+  servers map[string]*mclib.Server    // Last seen running, by task arn.
+  proxies map[string]*mclib.Proxy
+  proxyTaskDefs map[string]string
+  proxyEIPs map[string]string         // Elastic IP allocation ids by proxy name, to carry over a relaunch.
+  proxyAccess map[string]proxyAccess  // Last seen access and forced hosts by proxy name, to replay on a relaunch.
+  attempts map[string]int             // Relaunch attempts by server or proxy name.
+  pending map[string]bool             // Relaunches scheduled but not yet done.
+  evacuated map[string]bool           // Tasks we've moved off reclaimed instances, by task arn.
+  idleSince map[string]time.Time      // When we first saw a server empty, by task arn.
+  hibernateFailures map[string]hibernateFailure  // Failed hibernations, by task arn.
+  events []watchEvent
+}
+
+func newWatchdog(clusterName string, interval, backoff time.Duration, maxAttempts int, 
+  readyTimeout time.Duration, spotQueue string, sess *session.Session) (*watchdog) {
+  return &watchdog{
+    Cluster: clusterName,
+    Interval: interval,
+    Backoff: backoff,
+    MaxAttempts: maxAttempts,
+    ReadyTimeout: readyTimeout,
+    SpotQueue: spotQueue,
+    sess: sess,
+    stop: make(chan bool),
+    servers: make(map[string]*mclib.Server),
+    proxies: make(map[string]*mclib.Proxy),
+    proxyTaskDefs: make(map[string]string),
+    proxyEIPs: make(map[string]string),
+    proxyAccess: make(map[string]proxyAccess),
+    attempts: make(map[string]int),
+    pending: make(map[string]bool),
+    evacuated: make(map[string]bool),
+    idleSince: make(map[string]time.Time),
+    hibernateFailures: make(map[string]hibernateFailure),
+    events: make([]watchEvent, 0),
+  }
+}
+
+func doWatchServersCmd(sess *session.Session) (error) {
+  if currentWatchdog != nil {
+    return fmt.Errorf("Already watching cluster %s, use \"watch stop\" first.", currentWatchdog.Cluster)
+  }
+  wd := newWatchdog(currentCluster, watchIntervalArg, watchBackoffArg, watchMaxAttemptsArg, readyTimeoutArg,
+    watchSpotQueueArg, sess)
+  wd.IdleAfter = watchIdleAfterArg
+  wd.SleeperTaskDef = sleeperTaskDefArg
+  if err := wd.poll(); err != nil { return err }
+  wd.Start()
+  currentWatchdog = wd
+  fmt.Printf("%sWatching %d servers and %d proxies on %s every %s. Relaunching up to %d times.%s\n",
+    successColor, len(wd.servers), len(wd.proxies), wd.Cluster, wd.Interval, wd.MaxAttempts, resetColor)
+  if wd.SpotQueue != "" {
+    fmt.Printf("%sTaking spot interruption warnings from %s.%s\n", successColor, wd.SpotQueue, resetColor)
+  }
+  if wd.IdleAfter > 0 {
+    fmt.Printf("%sHibernating servers onto %s after %s with nobody on.%s\n", successColor, wd.SleeperTaskDef, wd.IdleAfter, resetColor)
+  }
+  return nil
+}
+
+func doWatchStopCmd() (error) {
+  if currentWatchdog == nil {
+    fmt.Printf("%sNot watching anything.%s\n", warnColor, resetColor)
+    return nil
+  }
+  currentWatchdog.Stop()
+  fmt.Printf("%sStopped watching %s.%s\n", successColor, currentWatchdog.Cluster, resetColor)
+  currentWatchdog = nil
+  return nil
+}
+
+func doWatchStatusCmd() (error) {
+  wd := currentWatchdog
+  if wd == nil {
+    fmt.Printf("%sNot watching anything.%s\n", warnColor, resetColor)
+    return nil
+  }
+  wd.mu.Lock()
+  defer wd.mu.Unlock()
+  fmt.Printf("%sWatching %s since %s (%s), every %s.%s\n", titleColor, wd.Cluster,
+    wd.Started.Local().Format(time.RFC1123), awslib.ShortDurationString(time.Since(wd.Started)), wd.Interval, resetColor)
+  if wd.SpotQueue != "" { fmt.Printf("%sSpot interruptions from %s.%s\n", titleColor, wd.SpotQueue, resetColor) }
+  w := tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+  fmt.Fprintf(w, "%sType\tName\tAttempts\tTask%s\n", titleColor, resetColor)
+  for arn, s := range wd.servers {
+    fmt.Fprintf(w, "%sserver\t%s\t%d\t%s%s\n", nullColor, s.Name, wd.attempts[s.Name], awslib.ShortArnString(&arn), resetColor)
+  }
+  for arn, p := range wd.proxies {
+    fmt.Fprintf(w, "%sproxy\t%s\t%d\t%s%s\n", nullColor, p.Name, wd.attempts[p.Name], awslib.ShortArnString(&arn), resetColor)
+  }
+  w.Flush()
+
+  if len(wd.events) > 0 {
+    fmt.Printf("\n%sEvents%s\n", titleColor, resetColor)
+    w = tabwriter.NewWriter(os.Stdout, 4, 8, 3, ' ', 0)
+    fmt.Fprintf(w, "%sTime\tName\tTask\tEvent%s\n", titleColor, resetColor)
+    for _, e := range wd.events {
+      fmt.Fprintf(w, "%s%s\t%s\t%s\t%s%s\n", nullColor, e.Time.Local().Format(time.RFC822), e.Name,
+        awslib.ShortArnString(&e.TaskArn), e.What, resetColor)
+    }
+    w.Flush()
+  }
+  return nil
+}
+
+func (wd *watchdog) Start() {
+  wd.Started = time.Now()
+  go func() {
+    ticker := time.NewTicker(wd.Interval)
+    defer ticker.Stop()
+    for {
+      select {
+      case <-wd.stop:
+        return
+      case <-ticker.C:
+        if err := wd.poll(); err != nil {
+          log.Error(fmt.Sprintf("Watchdog failed to poll %s: %s", wd.Cluster, err))
+        }
+      }
+    }
+  }()
+}
+
+func (wd *watchdog) Stop() {
+  close(wd.stop)
+}
+
+func (wd *watchdog) record(name, taskArn, what string) {
+  wd.events = append(wd.events, watchEvent{Time: time.Now(), Name: name, TaskArn: taskArn, What: what})
+}
+
+// poll refreshes what's running and deals with anything that's gone missing.
+func (wd *watchdog) poll() (error) {
+  proxies, dtm, err := mclib.GetProxies(wd.Cluster, wd.sess)
+  if err != nil { return err }
+  servers, err := getServers(wd.Cluster, wd.sess)
+  if err != nil { return err }
+  reclaimed, err := wd.reclaimedInstances()
+  if err != nil { log.Error(fmt.Sprintf("Watchdog failed to check for reclaimed instances on %s: %s", wd.Cluster, err)) }
+  counts := wd.playerCounts(servers)
+  var eips map[string]*ec2.Address
+  if len(proxies) > 0 {
+    if eips, err = instanceEIPs(wd.sess); err != nil { log.Error(fmt.Sprintf("Watchdog failed to get elastic IPs: %s", err)) }
+  }
+  access := make(map[string]proxyAccess)
+  for _, p := range proxies {
+    pa, err := proxyAccessOf(p, servers)
+    if err != nil {
+      log.Error(fmt.Sprintf("Watchdog failed to read access for proxy %s: %s", p.Name, err))
+      continue
+    }
+    access[p.Name] = pa
+  }
+
+  // Describe whatever's gone before taking the lock, ECS can be slow.
+  running := make(map[string]bool)
+  for _, s := range servers { running[*s.TaskArn] = true }
+  for _, p := range proxies { running[p.TaskArn] = true }
+  wd.mu.Lock()
+  goneArns := make([]string, 0)
+  for arn := range wd.servers {
+    if !running[arn] { goneArns = append(goneArns, arn) }
+  }
+  for arn := range wd.proxies {
+    if !running[arn] { goneArns = append(goneArns, arn) }
+  }
+  wd.mu.Unlock()
+  gone := make(map[string]stoppedTask)
+  for _, arn := range goneArns {
+    task, err := describeTask(wd.Cluster, arn, wd.sess)
+    gone[arn] = stoppedTask{Task: task, Err: err}
+  }
+
+  wd.mu.Lock()
+  defer wd.mu.Unlock()
+  cProxies := make(map[string]*mclib.Proxy)
+  for name, pa := range access { wd.proxyAccess[name] = pa }
+  for _, p := range proxies {
+    cProxies[p.TaskArn] = p
+    if dt, ok := dtm[p.TaskArn]; ok {
+      wd.proxyTaskDefs[p.Name] = *dt.TaskDefinition.TaskDefinitionArn
+      if id := dt.GetInstanceID(); id != nil && eips != nil {
+        if a, ok := eips[*id]; ok {
+          wd.proxyEIPs[p.Name] = *a.AllocationId
+        } else {
+          delete(wd.proxyEIPs, p.Name)
+        }
+      }
+    }
+  }
+  cServers := make(map[string]*mclib.Server)
+  for _, s := range servers {
+    // Hub servers run in the proxy task, the proxy covers them.
+    if _, ok := cProxies[*s.TaskArn]; ok { continue }
+    cServers[*s.TaskArn] = s
+  }
+
+  for arn, s := range wd.servers {
+    if _, ok := cServers[arn]; !ok {
+      s := s
+      expected := takeExpectedStop(arn)
+      // Someone's trying to log in to a hibernating server.
+      if !expected && hibernationRecord(s) != nil {
+        wd.runNow(s.Name, arn, "sleeper exited, waking", func() (error) {
+          _, err := wakeServer(s, proxies, wd.ReadyTimeout, wd.sess)
+          return err
+        })
+        continue
+      }
+      wd.taskGone(s.Name, arn, expected, gone[arn], func() (error) { return wd.relaunchServer(s, cProxies) })
+    }
+  }
+  for arn, p := range wd.proxies {
+    if _, ok := cProxies[arn]; !ok {
+      p := p
+      wd.taskGone(p.Name, arn, takeExpectedStop(arn), gone[arn], func() (error) { return wd.relaunchProxy(p) })
+    }
+  }
+
+  wd.checkIdle(cServers, counts, proxies)
+
+  // Move anything off instances that are going away.
+  for arn, s := range cServers {
+    if id := s.DeepTask.GetInstanceID(); id != nil && reclaimed[*id] {
+      s := s
+      wd.evacuate(s.Name, arn, *id, func() (error) { return wd.evacuateServer(s, proxies) })
+    }
+  }
+  for arn, p := range cProxies {
+    if dt, ok := dtm[arn]; ok {
+      if id := dt.GetInstanceID(); id != nil && reclaimed[*id] {
+        p := p
+        wd.evacuate(p.Name, arn, *id, func() (error) { return wd.evacuateProxy(p) })
+      }
+    }
+  }
+  for arn := range wd.evacuated {
+    _, isServer := cServers[arn]
+    _, isProxy := cProxies[arn]
+    if !isServer && !isProxy { delete(wd.evacuated, arn) }
+  }
+
+  wd.servers = cServers
+  wd.proxies = cProxies
+  return nil
+}
+
+// taskGone says why a task stopped and schedules a relaunch if it wasn't on purpose.
+// Called with the lock held.
+func (wd *watchdog) taskGone(name, taskArn string, expected bool, st stoppedTask, relaunch func() (error)) {
+  if expected {
+    wd.record(name, taskArn, "stopped as requested")
+    return
+  }
+
+  why := "unknown"
+  task, err := st.Task, st.Err
+  if task == nil && err == nil { err = fmt.Errorf("not described") }
+  if err == nil {
+    why = stoppedTaskSummary(task)
+    if task.StartedAt != nil && task.StoppedAt != nil && task.StoppedAt.Sub(*task.StartedAt) > watchStableAfter {
+      wd.attempts[name] = 0
+    }
+  } else {
+    why = fmt.Sprintf("failed to describe task: %s", err)
+  }
+  fmt.Printf("\n%s%s %s stopped unexpectedly: %s%s\n", failColor, time.Now().Local().Format(time.RFC1123),
+    name, why, resetColor)
+  wd.record(name, taskArn, fmt.Sprintf("stopped: %s", why))
+
+  if wd.pending[name] {
+    wd.record(name, taskArn, "relaunch already scheduled")
+    return
+  }
+  if wd.attempts[name] >= wd.MaxAttempts {
+    fmt.Printf("%sGiving up on %s after %d attempts.%s\n", failColor, name, wd.attempts[name], resetColor)
+    wd.record(name, taskArn, "gave up")
+    return
+  }
+
+  wd.attempts[name]++
+  delay := wd.backoff(wd.attempts[name])
+  wd.pending[name] = true
+  wd.record(name, taskArn, fmt.Sprintf("relaunch %d of %d in %s", wd.attempts[name], wd.MaxAttempts, delay))
+  fmt.Printf("%sRelaunching %s in %s (attempt %d of %d).%s\n", warnColor, name, delay,
+    wd.attempts[name], wd.MaxAttempts, resetColor)
+
+  time.AfterFunc(delay, func() {
+    err := relaunch()
+    wd.mu.Lock()
+    defer wd.mu.Unlock()
+    delete(wd.pending, name)
+    if err != nil {
+      fmt.Printf("\n%sWatchdog failed to relaunch %s: %s%s\n", failColor, name, err, resetColor)
+      wd.record(name, taskArn, fmt.Sprintf("relaunch failed: %s", err))
+    } else {
+      fmt.Printf("\n%sWatchdog relaunched %s.%s\n", successColor, name, resetColor)
+      wd.record(name, taskArn, "relaunched")
+    }
+  })
+}
+
+// runNow does something about a task in the background, now rather than after a backoff.
+// Called with the lock held.
+func (wd *watchdog) runNow(name, taskArn, what string, do func() (error)) {
+  if wd.pending[name] { return }
+  wd.pending[name] = true
+  fmt.Printf("\n%s%s %s: %s.%s\n", warnColor, time.Now().Local().Format(time.RFC1123), name, what, resetColor)
+  wd.record(name, taskArn, what)
+
+  go func() {
+    err := do()
+    wd.mu.Lock()
+    defer wd.mu.Unlock()
+    delete(wd.pending, name)
+    if err != nil {
+      fmt.Printf("\n%sWatchdog failed on %s (%s): %s%s\n", failColor, name, what, err, resetColor)
+      wd.record(name, taskArn, fmt.Sprintf("failed: %s", err))
+    } else {
+      fmt.Printf("\n%sWatchdog done with %s (%s).%s\n", successColor, name, what, resetColor)
+      wd.record(name, taskArn, "done")
+    }
+  }()
+}
+
+// Doubles with each attempt.
+func (wd *watchdog) backoff(attempt int) time.Duration {
+  d := wd.Backoff
+  for i := 1; i < attempt; i++ {
+    d *= 2
+  }
+  return d
+}
+
+// relaunchServer restarts from the latest server snapshot,
+// through the same path as server restart.
+func (wd *watchdog) relaunchServer(s *mclib.Server, proxies map[string]*mclib.Proxy) (error) {
+  var proxy *mclib.Proxy
+  for _, p := range proxies {
+    if proxied, err := p.IsServerProxied(s); err == nil && proxied {
+      proxy = p
+      break
+    }
+  }
+  _, err := restartServer(restartSpec{
+    Server: s,
+    Proxy: proxy,
+    TaskDef: *s.DeepTask.TaskDefinition.TaskDefinitionArn,
+    Cluster: wd.Cluster,
+    ReadyTimeout: wd.ReadyTimeout,
+  }, wd.sess)
+  return err
+}
+
+// relaunchProxy brings up a new proxy with what the old one last had:
+// its servers' access and forced hosts, elastic IP and DNS.
+func (wd *watchdog) relaunchProxy(p *mclib.Proxy) (error) {
+  wd.mu.Lock()
+  td, ok := wd.proxyTaskDefs[p.Name]
+  eip := wd.proxyEIPs[p.Name]
+  access := wd.proxyAccess[p.Name]
+  wd.mu.Unlock()
+  if !ok { td = defaultProxyTaskDef }
+  pl := placement{}
+  if task, err := describeTask(wd.Cluster, p.TaskArn, wd.sess); err == nil { pl = carriedPlacement(task, wd.sess) }
+  _, err := rebuildProxy(proxyRebuild{
+    Name: p.Name,
+    Old: p,
+    TaskDef: td,
+    Placement: pl,
+    Access: access,
+    EIP: eip,
+    Cluster: wd.Cluster,
+    ReadyTimeout: wd.ReadyTimeout,
+  }, wd.sess)
+  return err
+}
+
+// One line on why a task stopped: the task's reason and each container's exit.
+func stoppedTaskSummary(task *ecs.Task) (string) {
+  reason := "<none>"
+  if task.StoppedReason != nil { reason = *task.StoppedReason }
+  exits := make([]string, 0)
+  for _, c := range task.Containers {
+    code := "-"
+    if c.ExitCode != nil { code = fmt.Sprintf("%d", *c.ExitCode) }
+    exits = append(exits, fmt.Sprintf("%s=%s", *c.Name, code))
+  }
+  return fmt.Sprintf("%s [exit codes: %s]", reason, strings.Join(exits, ", "))
+}